@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// apiURL is the url for an api action; params are name, value pairs (already escaped).
+// It contains the key, so don't print it without redact.
+func apiURL(key, action string, params ...string) string {
+	return fmt.Sprint(
+		apiAddr(),
+		"/key/", key,
+		"/format/json",
+		"/action/", action,
+		"/", strings.Join(params, "/"),
+	)
+}
+
+// apiGet calls an api action, moving on to the next key if forvo refuses one.
+func apiGet(action string, params ...string) ([]byte, error) {
+	var fullAddr string
+	if *bench {
+		t0 := time.Now()
+		defer func() {
+			msg(fullAddr) // msg redacts the key
+			msg(time.Since(t0))
+		}()
+	}
+	for {
+		key, err := spendQuota()
+		if err != nil {
+			return nil, err
+		}
+		fullAddr = apiURL(key, action, params...)
+		if action == "word-pronunciations" {
+			msg("downloading pronunciation list...")
+		} else {
+			msg("asking forvo for", action+"...")
+		}
+		resp, err := httpClient.Get(fullAddr)
+		if err != nil {
+			return nil, redactErr(err)
+		}
+		buf, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			if keyRefused(resp.StatusCode, string(buf)) {
+				msg("forvo refused api key", keyID(key), "for today; trying the next one")
+				refuseKey(key)
+				continue
+			}
+			return nil, redactErr(fmt.Errorf("forvo complained: HTPP %s (%s)", resp.Status, string(buf)))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading forvo response body: %s", err)
+		}
+		return buf, nil
+	}
+}
+
+// how long cachedAPIGet's answers are good for; searches and popular words change as pronunciations are added
+const (
+	searchMaxAge = 7 * 24 * time.Hour
+	listMaxAge   = 30 * 24 * time.Hour
+)
+
+// cachedAPIGet is apiGet, but keeps the answer in the cache dir for up to maxAge (unless -refresh).
+func cachedAPIGet(maxAge time.Duration, action string, params ...string) ([]byte, error) {
+	name := action
+	for _, p := range params {
+		if u, err := url.PathUnescape(p); err == nil {
+			p = u
+		}
+		name += "/" + sanitizeFname(p)
+	}
+	fname := cacheDir + "/.api/" + name + ".json"
+	if fi, err := os.Stat(fname); err == nil && !*refreshCache && time.Since(fi.ModTime()) < maxAge {
+		if buf, err := ioutil.ReadFile(fname); err == nil {
+			return buf, nil
+		}
+	}
+	buf, err := apiGet(action, params...)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(path.Dir(fname), 0777); err == nil {
+		err = ioutil.WriteFile(fname, buf, 0666)
+	}
+	if err != nil {
+		msg("warning: could not save", action, "to cache:", err)
+	}
+	return buf, nil
+}
+
+// WordInfo is a word as returned by the search and popular-words actions.
+type WordInfo struct {
+	Id                int64
+	Word              string
+	Original          string
+	NumPronunciations int            `json:"num_pronunciations"`
+	Standard          *Pronunciation `json:"standard_pronunciation"`
+}
+
+type Language struct {
+	Code string
+	En   string // name, in English
+}
+
+func decodeItems(action string, buf []byte, items interface{}) error {
+	var r struct{ Items json.RawMessage }
+	if err := json.Unmarshal(buf, &r); err != nil {
+		return fmt.Errorf("forvo %s response «%s» could not be unmarshalled as json: %v", action, string(buf), err)
+	}
+	if len(r.Items) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(r.Items, items); err != nil {
+		return fmt.Errorf("forvo %s response «%s» could not be unmarshalled as json: %v", action, string(buf), err)
+	}
+	return nil
+}
+
+// SearchWords finds words starting with prefix; with pronounced, just the ones that have pronunciations.
+func SearchWords(lang, prefix string, pronounced bool) ([]WordInfo, error) {
+	action := "words-search"
+	if pronounced {
+		action = "pronounced-words-search"
+	}
+	buf, err := cachedAPIGet(searchMaxAge, action, "search", url.PathEscape(prefix), "language", lang)
+	if err != nil {
+		return nil, err
+	}
+	var words []WordInfo
+	return words, decodeItems(action, buf, &words)
+}
+
+func PopularWords(lang string, limit int) ([]WordInfo, error) {
+	buf, err := cachedAPIGet(searchMaxAge, "popular-pronounced-words", "language", lang, "limit", fmt.Sprint(limit))
+	if err != nil {
+		return nil, err
+	}
+	var words []WordInfo
+	return words, decodeItems("popular-pronounced-words", buf, &words)
+}
+
+func Languages() ([]Language, error) {
+	return LanguagesWith(0)
+}
+
+// LanguagesWith lists the languages with at least min pronunciations.
+func LanguagesWith(min int) ([]Language, error) {
+	params := []string{"order", "name"}
+	if min > 0 {
+		params = append(params, "min-pronunciations", fmt.Sprint(min))
+	}
+	buf, err := cachedAPIGet(listMaxAge, "language-list", params...)
+	if err != nil {
+		return nil, err
+	}
+	var langs []Language
+	return langs, decodeItems("language-list", buf, &langs)
+}
+
+// StandardPronunciation is forvo's pick of the best pronunciation of the word, if it has one.
+func StandardPronunciation(req Req) (*Pronunciation, error) {
+	buf, err := cachedAPIGet(listMaxAge, "standard-pronunciation", "word", apiWord(req.Word), "language", req.LangCode)
+	if err != nil {
+		return nil, err
+	}
+	var items []Pronunciation
+	if err := decodeItems("standard-pronunciation", buf, &items); err != nil || len(items) == 0 {
+		return nil, err
+	}
+	return &items[0], nil
+}
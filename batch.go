@@ -0,0 +1,256 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"sync"
+)
+
+var batch = flag.Bool("batch", false, "download (but don't play) pronunciations for every word in the files given as arguments (or stdin), then print a summary")
+var batchFmt = flag.String("batchfmt", "", "format of the -batch/-enqueue word lists: text (one word per line), csv or tsv; by default guessed from the file extension")
+var col = flag.Int("col", 1, "with -batch or -enqueue, take words from this `column` of csv/tsv files (numbered from 1)")
+var jobs = flag.Int("j", 4, "with -batch, look up at most `J` words at a time")
+
+// readWords reads a word list, which is plain text (one word per line, # for comments), csv or tsv;
+// for csv and tsv, the words are taken from column col (numbered from 1).
+func readWords(r io.Reader, format string, col int) ([]string, error) {
+	if col < 1 {
+		return nil, fmt.Errorf("bad column %d -- columns are numbered from 1", col)
+	}
+	var words []string
+	switch format {
+	case "", "text", "txt":
+		b, err := ioutil.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range strings.Split(string(b), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			words = append(words, line)
+		}
+	case "csv", "tsv":
+		cr := csv.NewReader(r)
+		if format == "tsv" {
+			cr.Comma = '\t'
+		}
+		cr.FieldsPerRecord = -1
+		cr.LazyQuotes = true
+		cr.Comment = '#'
+		for {
+			rec, err := cr.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			if col > len(rec) {
+				continue
+			}
+			if w := strings.TrimSpace(rec[col-1]); w != "" {
+				words = append(words, w)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown word list format %q (want text, csv or tsv)", format)
+	}
+	return words, nil
+}
+
+func readWordFile(fname string) ([]string, error) {
+	format := *batchFmt
+	if fname == "-" {
+		return readWords(os.Stdin, format, *col)
+	}
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(path.Ext(fname)), ".")
+		if format != "csv" && format != "tsv" {
+			format = "text"
+		}
+	}
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	words, err := readWords(f, format, *col)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", fname, err)
+	}
+	return words, nil
+}
+
+type batchResult struct {
+	Word   string
+	Found  int  // number of pronunciations downloaded
+	Listed bool // whether the pronunciation list was got, from the cache or forvo
+	Err    error
+}
+
+// prefetch downloads the pronunciation list and the mp3s for a word, without playing anything.
+func prefetch(req Req) batchResult {
+	resp, err := CacheResp(req)
+	if err != nil {
+		return batchResult{Word: req.Word, Err: err}
+	}
+	res := batchResult{Word: req.Word, Listed: true}
+	CacheMP3s(req, *resp, func(mp3 MaybeMP3) {
+		if mp3.Err != nil {
+			if res.Err == nil {
+				res.Err = fmt.Errorf("could not download mp3: %v", mp3.Err)
+			}
+			return
+		}
+		res.Found++
+	})
+	if res.Found > 0 {
+		res.Err = nil // partial success is good enough
+	}
+	return res
+}
+
+// readWordFiles reads the words from all of the word lists (or stdin if there are none).
+func readWordFiles(fnames []string) []string {
+	if len(fnames) == 0 {
+		fnames = []string{"-"}
+	}
+	var words []string
+	for _, fname := range fnames {
+		ws, err := readWordFile(fname)
+		if err != nil {
+			fatal("could not read word list:", err)
+		}
+		words = append(words, ws...)
+	}
+	return words
+}
+
+// prefetchWord prefetches word in the first of langs that has pronunciations (or all of them, with -compare);
+// before, if non-nil, is called before each language is tried, and can stop it with an error; after, if
+// non-nil, is told how each language went.
+func prefetchWord(word string, langs []string, before func(Req) error, after func(Req, batchResult)) batchResult {
+	var res batchResult
+	found := 0
+	for _, l := range langs {
+		req := Req{normalizeWord(word, l), l}
+		if before != nil {
+			if err := before(req); err != nil {
+				res.Err = err
+				break
+			}
+		}
+		res = prefetch(req)
+		if after != nil {
+			after(req, res)
+		}
+		found += res.Found
+		if res.Err != nil || (res.Found > 0 && !*compare) {
+			break
+		}
+	}
+	res.Word = word
+	res.Found = found
+	return res
+}
+
+func runBatch(fnames []string) {
+	var words []string
+	seen := map[string]bool{}
+	for _, w := range readWordFiles(fnames) {
+		if k := normalizeWord(w, firstLang()); !seen[k] {
+			seen[k] = true
+			words = append(words, w)
+		}
+	}
+	if n := quotaLeft(); n >= 0 {
+		msg(len(words), "words;", n, "forvo requests left today")
+	}
+
+	results := make([]batchResult, len(words))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	done := 0
+	nj := *jobs
+	if nj < 1 {
+		nj = 1
+	}
+	for j := 0; j < nj; j++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				res := prefetchWord(words[i], langChain(), nil, nil)
+				results[i] = res
+				mu.Lock()
+				done++
+				switch {
+				case res.Err != nil:
+					msgf("[%d/%d] %s: error: %v\n", done, len(words), res.Word, res.Err)
+				case res.Found == 0:
+					msgf("[%d/%d] %s: no pronunciations\n", done, len(words), res.Word)
+				default:
+					msgf("[%d/%d] %s: %d pronunciations\n", done, len(words), res.Word, res.Found)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := range words {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	printBatchSummary(results)
+}
+
+func printBatchSummary(results []batchResult) {
+	var found, missing, errored, quotaed []string
+	for _, res := range results {
+		switch {
+		case errors.Is(res.Err, errQuota):
+			quotaed = append(quotaed, res.Word)
+		case res.Err != nil:
+			errored = append(errored, res.Word+" ("+res.Err.Error()+")")
+		case res.Found == 0:
+			missing = append(missing, res.Word)
+		default:
+			found = append(found, res.Word)
+		}
+	}
+	if *jsonOut {
+		jsonMu.Lock()
+		defer jsonMu.Unlock()
+		writeJSON(struct {
+			Event                             string
+			Found, Missing, Errored, NotTried []string
+		}{"batch", found, missing, errored, quotaed})
+		return
+	}
+	msg()
+	msg("found:", len(found))
+	msg("missing:", len(missing))
+	for _, w := range missing {
+		msg(" ", w)
+	}
+	msg("errored:", len(errored))
+	for _, w := range errored {
+		msg(" ", w)
+	}
+	if len(quotaed) > 0 {
+		msg("not tried, out of quota:", len(quotaed))
+		for _, w := range quotaed {
+			msg(" ", w)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadWords(t *testing.T) {
+	for _, tc := range []struct {
+		in, format string
+		col        int
+		want       []string
+	}{
+		{"Hund\n  Katze \n\n# comment\nder Hund\n", "text", 1, []string{"Hund", "Katze", "der Hund"}},
+		{"Hund\r\nKatze\r\n", "", 1, []string{"Hund", "Katze"}},
+		{"Hund,dog\nKatze,cat\n", "csv", 1, []string{"Hund", "Katze"}},
+		{"Hund,dog\nKatze,cat\n", "csv", 2, []string{"dog", "cat"}},
+		{"Hund,dog\nMaus\n# Vogel,bird\n", "csv", 2, []string{"dog"}},
+		{"\"Hund, der\",dog\n", "csv", 1, []string{"Hund, der"}},
+		{"Hund\tdog\nKatze\t cat \n", "tsv", 2, []string{"dog", "cat"}},
+	} {
+		got, err := readWords(strings.NewReader(tc.in), tc.format, tc.col)
+		if err != nil {
+			t.Errorf("readWords(%q, %q, %d): %v", tc.in, tc.format, tc.col, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("readWords(%q, %q, %d) = %q, want %q", tc.in, tc.format, tc.col, got, tc.want)
+		}
+	}
+	if _, err := readWords(strings.NewReader("Hund,dog\n"), "csv", 0); err == nil {
+		t.Error("column 0 wasn't refused")
+	}
+	if _, err := readWords(strings.NewReader("Hund\n"), "xlsx", 1); err == nil {
+		t.Error("unknown format wasn't refused")
+	}
+}
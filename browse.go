@@ -0,0 +1,128 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+// commands for browsing what forvo has, before spending lookups on it
+
+func runSearch(args []string) {
+	fs := flag.NewFlagSet("search", flag.ExitOnError)
+	all := fs.Bool("all", false, "include words nobody has pronounced yet")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fatal("usage: forvosay -lang <lang> search [-all] <prefix>")
+	}
+	needLang()
+	words, err := SearchWords(firstLang(), normalizeWord(fs.Arg(0), firstLang()), !*all)
+	if err != nil {
+		fatal(err)
+	}
+	printWords(words)
+}
+
+func runPopular(args []string) {
+	fs := flag.NewFlagSet("popular", flag.ExitOnError)
+	limit := fs.Int("limit", 50, "show this many `words`")
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		fatal("unknown argument:", fs.Arg(0))
+	}
+	needLang()
+	words, err := PopularWords(firstLang(), *limit)
+	if err != nil {
+		fatal(err)
+	}
+	printWords(words)
+}
+
+func printWords(words []WordInfo) {
+	if *jsonOut {
+		writeJSON(words)
+		return
+	}
+	if len(words) == 0 {
+		msg("no words")
+	}
+	for _, w := range words {
+		if w.NumPronunciations > 0 {
+			msgf("%s (%d)\n", w.Original, w.NumPronunciations)
+		} else {
+			msg(w.Original)
+		}
+	}
+}
+
+func runLangs(args []string) {
+	fs := flag.NewFlagSet("langs", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, `usage: forvosay langs [-min N]
+
+Lists the languages forvo knows, with how many words of each are in the cache.
+(Forvo's language list doesn't give the number of pronunciations per language;
+-min is passed on to forvo to filter by it, though.)`)
+		fs.PrintDefaults()
+	}
+	min := fs.Int("min", 0, "only languages with at least this many `pronunciations` on forvo")
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		fatal("unknown argument:", fs.Arg(0))
+	}
+	langs, err := LanguagesWith(*min)
+	if err != nil {
+		fatal(err)
+	}
+	type langInfo struct {
+		Language
+		Cached int // words in the cache
+	}
+	var infos []langInfo
+	for _, l := range langs {
+		infos = append(infos, langInfo{l, len(cachedWords(l.Code))})
+	}
+	if *jsonOut {
+		writeJSON(infos)
+		return
+	}
+	for _, l := range infos {
+		if l.Cached > 0 {
+			msgf("%-6s %s (%d words cached)\n", l.Code, l.En, l.Cached)
+		} else {
+			msgf("%-6s %s\n", l.Code, l.En)
+		}
+	}
+}
+
+func runStandard(args []string) {
+	if len(args) != 1 {
+		fatal("usage: forvosay -lang <lang> standard <word>")
+	}
+	needLang()
+	req := Req{normalizeWord(args[0], firstLang()), firstLang()}
+	item, err := StandardPronunciation(req)
+	if err != nil {
+		fatal(err)
+	}
+	if item == nil {
+		fatal("no standard pronunciation for", req.Word)
+	}
+	if *jsonOut {
+		writeJSON(item)
+		return
+	}
+	ch := make(chan MaybeMP3, 1)
+	if err := os.MkdirAll(req.CacheDir(), 0777); err != nil {
+		fatal(err)
+	}
+	cachedMP3(*item, req.CacheDir()+"/standard.mp3", ch)
+	mp3 := <-ch
+	if mp3.Err != nil {
+		fatal("could not download mp3:", mp3.Err)
+	}
+	msg(formatPlay(*item, mp3.Fname, 1, pronOf(req), formatTmpl))
+	if err := PlayMP3(mp3.Fname); err != nil {
+		fatal("could not play mp3:", err)
+	}
+}
@@ -2,7 +2,6 @@ package main
 
 import (
 	"encoding/json"
-	"fmt"
 	"io/ioutil"
 	"os"
 	"path"
@@ -22,8 +21,9 @@ func CacheResp(req Req) (*Resp, error) {
 		return nil, err
 	}
 	if err := saveRespToCache(req, *resp); err != nil {
-		fmt.Println("warning: could not save pronunciation list to cache:", err)
+		msg("warning: could not save pronunciation list to cache:", err)
 	}
+	notePronounced(req, *resp)
 	return resp, nil
 }
 
@@ -63,3 +63,33 @@ func sanitizeFname(s string) string {
 	s = strings.Replace(s, ":", "48", -1)
 	return s
 }
+
+// cachedWords lists the words we have a pronunciation list for (with their file names sanitized).
+func cachedWords(lang string) []string {
+	fis, err := ioutil.ReadDir(cacheDir + "/" + lang)
+	if err != nil {
+		return nil
+	}
+	var words []string
+	for _, fi := range fis {
+		if fi.IsDir() && !strings.HasPrefix(fi.Name(), ".") {
+			words = append(words, fi.Name())
+		}
+	}
+	return words
+}
+
+// cachedLangs lists the languages we have cached anything for.
+func cachedLangs() []string {
+	fis, err := ioutil.ReadDir(cacheDir)
+	if err != nil {
+		return nil
+	}
+	var langs []string
+	for _, fi := range fis {
+		if fi.IsDir() && !strings.HasPrefix(fi.Name(), ".") {
+			langs = append(langs, fi.Name())
+		}
+	}
+	return langs
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+
+	"github.com/atotto/clipboard"
+)
+
+var selection = flag.String("selection", "clipboard", "which selection to watch on X11/wayland: clipboard (what's copied) or primary (what's selected; needs wl-paste, xclip or xsel). On X11, changes are noticed right away if the optional clipnotify program is installed, and otherwise by polling")
+var pollMax = flag.Duration("pollmax", 300*time.Millisecond, "when no clipboard change notifications are available, poll the clipboard at most this far apart (sooner after a change)")
+
+const pollMin = 20 * time.Millisecond
+
+// the clipboard tools we have, as found by checkSelection at startup
+var clipTools struct {
+	wayland    bool // wl-paste, on wayland
+	xclip      bool
+	clipnotify bool // on X11
+}
+
+// checkSelection checks -selection, and which clipboard tools we have.
+func checkSelection() error {
+	_, err := exec.LookPath("wl-paste")
+	clipTools.wayland = os.Getenv("WAYLAND_DISPLAY") != "" && err == nil
+	_, err = exec.LookPath("xclip")
+	clipTools.xclip = err == nil
+	_, err = exec.LookPath("clipnotify")
+	clipTools.clipnotify = os.Getenv("DISPLAY") != "" && err == nil
+	switch *selection {
+	case "clipboard":
+		return nil
+	case "primary":
+		if runtime.GOOS == "darwin" || runtime.GOOS == "windows" {
+			return errors.New("-selection primary needs X11 or wayland")
+		}
+		return nil
+	}
+	return errors.New("bad -selection: " + *selection + " -- expected clipboard or primary")
+}
+
+// readClipboard reads the -selection.
+func readClipboard() (string, error) {
+	if *selection != "primary" {
+		return clipboard.ReadAll()
+	}
+	var cmd *exec.Cmd
+	if clipTools.wayland {
+		cmd = exec.Command("wl-paste", "--no-newline", "--primary")
+	} else if clipTools.xclip {
+		cmd = exec.Command("xclip", "-out", "-selection", "primary")
+	} else {
+		cmd = exec.Command("xsel", "--primary", "--output")
+	}
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+// clipWatcher says when to read the clipboard again: when wl-paste --watch or clipnotify (X11's XFixes
+// selection events) say it's changed, or else every so often, less often the longer it stays the same.
+type clipWatcher struct {
+	changes  chan struct{} // nil when polling
+	interval time.Duration
+}
+
+func newClipWatcher() *clipWatcher {
+	cw := &clipWatcher{interval: pollMin}
+	var cmd func() *exec.Cmd
+	oneShot := false
+	if clipTools.wayland {
+		args := []string{"--watch", "echo"}
+		if *selection == "primary" {
+			args = append([]string{"--primary"}, args...)
+		}
+		cmd = func() *exec.Cmd { return exec.Command("wl-paste", args...) }
+	} else if clipTools.clipnotify {
+		cmd = func() *exec.Cmd { return exec.Command("clipnotify", "-s", *selection) }
+		oneShot = true
+	}
+	if cmd != nil {
+		cw.changes = make(chan struct{}, 1)
+		go cw.notify(cmd, oneShot)
+	}
+	return cw
+}
+
+// notify runs the notifier, passing on each change; wl-paste --watch prints a line for each, while
+// clipnotify exits after each (oneShot, so it's run again). If the notifier fails, we go back to polling.
+func (cw *clipWatcher) notify(cmd func() *exec.Cmd, oneShot bool) {
+	defer close(cw.changes)
+	for {
+		c := cmd()
+		out, err := c.StdoutPipe()
+		if err == nil {
+			err = c.Start()
+		}
+		if err != nil {
+			msg("warning: could not watch the clipboard, polling it instead:", err)
+			return
+		}
+		b := bufio.NewScanner(out)
+		for b.Scan() {
+			cw.signal()
+		}
+		if err := c.Wait(); err != nil {
+			msg("warning: stopped watching the clipboard, polling it instead:", err)
+			return
+		}
+		if !oneShot {
+			return // wl-paste --watch shouldn't ever stop
+		}
+		cw.signal()
+	}
+}
+
+func (cw *clipWatcher) signal() {
+	select {
+	case cw.changes <- struct{}{}:
+	default:
+	}
+}
+
+// wait blocks until the clipboard may have changed, or until woken to look anyway.
+func (cw *clipWatcher) wait(wake <-chan struct{}) {
+	if cw.changes == nil {
+		select {
+		case <-time.After(cw.interval):
+		case <-wake:
+		}
+		return
+	}
+	select {
+	case _, ok := <-cw.changes:
+		if !ok {
+			cw.changes = nil
+		}
+	case <-wake:
+	case <-time.After(2 * time.Second): // just in case a notification goes missing
+	}
+}
+
+// changed tells cw whether the clipboard had changed when read, so it can poll less often while it doesn't.
+func (cw *clipWatcher) changed(yes bool) {
+	if yes {
+		cw.interval = pollMin
+		return
+	}
+	if cw.interval = cw.interval * 3 / 2; cw.interval > *pollMax {
+		cw.interval = *pollMax
+	}
+}
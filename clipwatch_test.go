@@ -0,0 +1,36 @@
+package main
+
+import (
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestClipWatcherBackoff(t *testing.T) {
+	cw := &clipWatcher{interval: pollMin}
+	for i := 0; i < 20; i++ {
+		cw.changed(false)
+	}
+	if cw.interval != *pollMax {
+		t.Errorf("after no changes, polling every %v, want -pollmax %v", cw.interval, *pollMax)
+	}
+	cw.changed(true)
+	if cw.interval != pollMin {
+		t.Errorf("after a change, polling every %v, want %v", cw.interval, pollMin)
+	}
+}
+
+func TestClipWatcherNotify(t *testing.T) {
+	cw := &clipWatcher{interval: pollMin, changes: make(chan struct{}, 1)}
+	go cw.notify(func() *exec.Cmd { return exec.Command("echo") }, false)
+	cw.wait(nil) // the change echo reported
+	cw.wait(nil) // echo's gone, so back to polling
+	if cw.changes != nil {
+		t.Fatal("still waiting for notifications after the notifier exited")
+	}
+	t0 := time.Now()
+	cw.wait(nil)
+	if d := time.Since(t0); d > time.Second {
+		t.Errorf("polling waited %v", d)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"flag"
+	"strings"
+)
+
+var country = flag.String("country", "", "only play pronunciations by speakers from these comma-separated `countries` (names, or ISO codes like MX or MEX)")
+
+// countryCodes maps ISO 3166 codes to the country names forvo uses, for the countries most often asked about.
+var countryCodes = map[string]string{
+	"ar": "Argentina", "arg": "Argentina",
+	"at": "Austria", "aut": "Austria",
+	"au": "Australia", "aus": "Australia",
+	"be": "Belgium", "bel": "Belgium",
+	"bo": "Bolivia", "bol": "Bolivia",
+	"br": "Brazil", "bra": "Brazil",
+	"ca": "Canada", "can": "Canada",
+	"ch": "Switzerland", "che": "Switzerland",
+	"cl": "Chile", "chl": "Chile",
+	"cn": "China", "chn": "China",
+	"co": "Colombia", "col": "Colombia",
+	"cu": "Cuba", "cub": "Cuba",
+	"de": "Germany", "deu": "Germany",
+	"ec": "Ecuador", "ecu": "Ecuador",
+	"es": "Spain", "esp": "Spain",
+	"fr": "France", "fra": "France",
+	"gb": "United Kingdom", "gbr": "United Kingdom", "uk": "United Kingdom",
+	"hk": "Hong Kong", "hkg": "Hong Kong",
+	"ie": "Ireland", "irl": "Ireland",
+	"in": "India", "ind": "India",
+	"it": "Italy", "ita": "Italy",
+	"jp": "Japan", "jpn": "Japan",
+	"kr": "Korea", "kor": "Korea",
+	"mo": "Macao", "mac": "Macao",
+	"mx": "Mexico", "mex": "Mexico",
+	"nl": "Netherlands", "nld": "Netherlands",
+	"nz": "New Zealand", "nzl": "New Zealand",
+	"pe": "Peru", "per": "Peru",
+	"pl": "Poland", "pol": "Poland",
+	"pr": "Puerto Rico", "pri": "Puerto Rico",
+	"pt": "Portugal", "prt": "Portugal",
+	"ru": "Russia", "rus": "Russia",
+	"sg": "Singapore", "sgp": "Singapore",
+	"tw": "Taiwan", "twn": "Taiwan",
+	"ua": "Ukraine", "ukr": "Ukraine",
+	"us": "United States", "usa": "United States",
+	"uy": "Uruguay", "ury": "Uruguay",
+	"ve": "Venezuela", "ven": "Venezuela",
+}
+
+// countryMatches reports whether the country of a pronunciation is one of the -country list.
+func countryMatches(itemCountry, countries string) bool {
+	itemCountry = strings.ToLower(itemCountry)
+	for _, c := range strings.Split(countries, ",") {
+		c = strings.ToLower(strings.TrimSpace(c))
+		if c == "" {
+			continue
+		}
+		if name, ok := countryCodes[c]; ok {
+			c = strings.ToLower(name)
+		}
+		// forvo's names are sometimes long winded ("Korea, Republic of"), so the part before the comma will do
+		if itemCountry == c || strings.SplitN(itemCountry, ",", 2)[0] == c {
+			return true
+		}
+	}
+	return false
+}
+
+// onlyCountries filters resp down to the pronunciations from the -country list (if any).
+func onlyCountries(resp Resp, countries string) Resp {
+	if strings.TrimSpace(countries) == "" {
+		return resp
+	}
+	resp2 := Resp{}
+	for _, item := range resp.Items {
+		if countryMatches(item.Country, countries) {
+			resp2.Items = append(resp2.Items, item)
+		}
+	}
+	return resp2
+}
@@ -0,0 +1,21 @@
+package main
+
+import "testing"
+
+func TestCountryMatches(t *testing.T) {
+	for _, tc := range []struct {
+		item, countries string
+		want            bool
+	}{
+		{"Mexico", "MX", true},
+		{"Mexico", "mexico", true},
+		{"Germany", "ge", false},
+		{"Germany", "es,de", true},
+		{"Korea, Republic of", "kr", true},
+		{"Korea, Republic of", "rep", false},
+	} {
+		if got := countryMatches(tc.item, tc.countries); got != tc.want {
+			t.Errorf("countryMatches(%q, %q) = %v, want %v", tc.item, tc.countries, got, tc.want)
+		}
+	}
+}
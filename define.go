@@ -0,0 +1,484 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var defineFiles = flag.String("define", "", "print definitions from local dictionaries after each lookup: comma-separated `lang=file` (or just file, for every language) of StarDict .ifo, dictd .index or wiktextract .jsonl files")
+
+// a dictionary gives the entries it has for a word, in full.
+type dictionary interface {
+	define(word string) ([]string, error)
+}
+
+var dictionaries struct {
+	mu     sync.Mutex // held while loading or resetting
+	once   sync.Once
+	byLang map[string][]dictionary // "" for every language
+}
+
+// resetDictionaries has the -define dictionaries opened again when next needed.
+func resetDictionaries() {
+	dictionaries.mu.Lock()
+	defer dictionaries.mu.Unlock()
+	dictionaries.once = sync.Once{}
+	dictionaries.byLang = nil
+}
+
+func loadDictionaries() {
+	dictionaries.byLang = map[string][]dictionary{}
+	optionsMu.RLock()
+	files := *defineFiles
+	optionsMu.RUnlock()
+	if files == "" {
+		return
+	}
+	for _, spec := range strings.Split(files, ",") {
+		lang, fname := "", strings.TrimSpace(spec)
+		if i := strings.Index(fname, "="); i >= 0 {
+			lang, fname = fname[:i], fname[i+1:]
+		}
+		d, err := openDictionary(fname)
+		if err != nil {
+			msg("warning: could not open -define dictionary:", err)
+			continue
+		}
+		dictionaries.byLang[lang] = append(dictionaries.byLang[lang], d)
+	}
+}
+
+// dictionariesFor are lang's -define dictionaries, then the ones for every language, opened when first needed.
+func dictionariesFor(lang string) []dictionary {
+	dictionaries.mu.Lock()
+	defer dictionaries.mu.Unlock()
+	dictionaries.once.Do(loadDictionaries)
+	return append(append([]dictionary{}, dictionaries.byLang[lang]...), dictionaries.byLang[""]...)
+}
+
+func openDictionary(fname string) (dictionary, error) {
+	switch ext := filepath.Ext(strings.TrimSuffix(fname, ".gz")); ext {
+	case ".ifo":
+		return openStarDict(fname)
+	case ".index":
+		return openDictd(fname)
+	case ".jsonl", ".json":
+		return openWiktDict(fname)
+	default:
+		return nil, fmt.Errorf("%s: don't know what kind of dictionary %q is", fname, ext)
+	}
+}
+
+// definitions are the full entries for word in lang's dictionaries (and the ones for every language).
+func definitions(word, lang string) []string {
+	var defs []string
+	for _, d := range dictionariesFor(lang) {
+		entries, err := d.define(word)
+		if err == nil && len(entries) == 0 && strings.ToLower(word) != word {
+			entries, err = d.define(strings.ToLower(word))
+		}
+		if err != nil {
+			msg("warning: could not read dictionary:", err)
+		}
+		defs = append(defs, entries...)
+	}
+	return defs
+}
+
+// conciseDefs are the first few lines of each entry, to print after a lookup.
+func conciseDefs(defs []string) []string {
+	const maxLines, maxWidth = 3, 100
+	var lines []string
+	for _, d := range defs {
+		for i, line := range nonEmptyLines(d) {
+			if i == maxLines {
+				break
+			}
+			if r := []rune(line); len(r) > maxWidth {
+				line = string(r[:maxWidth-1]) + "…"
+			}
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func nonEmptyLines(s string) []string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// printDefinitions prints what the -define dictionaries say about word, in the first language of
+// langs that has anything, returning the (full) entries.
+func printDefinitions(word string, langs []string, full bool) []string {
+	for _, l := range langs {
+		// dictionaries keep the case of nouns (Hund) and names, so try the word as it is first
+		defs := definitions(cleanWord(word), l)
+		if w := normalizeWord(word, l); len(defs) == 0 && w != cleanWord(word) {
+			defs = definitions(w, l)
+		}
+		if len(defs) == 0 {
+			continue
+		}
+		if full {
+			for _, d := range defs {
+				msg(strings.TrimSpace(d))
+				msg()
+			}
+		} else {
+			for _, line := range conciseDefs(defs) {
+				msg("  " + line)
+			}
+		}
+		return defs
+	}
+	if full {
+		msgf("no definition of `%v`\n", word)
+	}
+	return nil
+}
+
+// readMaybeDz reads a whole .dict (or .idx) file, or its gzipped/dictzipped .dz or .gz.
+func readMaybeDz(fname string) ([]byte, error) {
+	for _, suffix := range []string{".dz", ".gz"} {
+		if _, err := os.Stat(fname + suffix); err == nil {
+			f, err := os.Open(fname + suffix)
+			if err != nil {
+				return nil, err
+			}
+			defer f.Close()
+			z, err := gzip.NewReader(f) // dictzip is gzip, with an index for random access that we don't use
+			if err != nil {
+				return nil, fmt.Errorf("%s%s: %v", fname, suffix, err)
+			}
+			return ioutil.ReadAll(z)
+		}
+	}
+	return ioutil.ReadFile(fname)
+}
+
+// dictEntry is where an entry is in a .dict file.
+type dictEntry struct{ off, size int64 }
+
+// starDict is a StarDict dictionary: word.ifo (metadata), word.idx (sorted index) and word.dict[.dz].
+type starDict struct {
+	typeSeq string // sametypesequence: the kinds of data in each entry, if they're all the same
+	index   map[string][]dictEntry
+	dict    []byte
+}
+
+func openStarDict(ifo string) (*starDict, error) {
+	buf, err := ioutil.ReadFile(ifo)
+	if err != nil {
+		return nil, err
+	}
+	info := map[string]string{}
+	for _, line := range strings.Split(string(buf), "\n") {
+		if i := strings.Index(line, "="); i > 0 {
+			info[line[:i]] = strings.TrimSpace(line[i+1:])
+		}
+	}
+	base := strings.TrimSuffix(ifo, ".ifo")
+	idx, err := readMaybeDz(base + ".idx")
+	if err != nil {
+		return nil, err
+	}
+	d := &starDict{typeSeq: info["sametypesequence"], index: map[string][]dictEntry{}}
+	offBytes := 4
+	if info["idxoffsetbits"] == "64" {
+		offBytes = 8
+	}
+	for len(idx) > 0 {
+		i := bytes.IndexByte(idx, 0)
+		if i < 0 || len(idx) < i+1+offBytes+4 {
+			return nil, fmt.Errorf("%s.idx: truncated", base)
+		}
+		word := string(idx[:i])
+		idx = idx[i+1:]
+		var e dictEntry
+		if offBytes == 8 {
+			e.off = int64(binary.BigEndian.Uint64(idx))
+		} else {
+			e.off = int64(binary.BigEndian.Uint32(idx))
+		}
+		e.size = int64(binary.BigEndian.Uint32(idx[offBytes:]))
+		idx = idx[offBytes+4:]
+		d.index[word] = append(d.index[word], e)
+	}
+	if d.dict, err = readMaybeDz(base + ".dict"); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *starDict) define(word string) ([]string, error) {
+	var defs []string
+	for _, e := range d.index[word] {
+		if e.off+e.size > int64(len(d.dict)) {
+			return defs, errors.New("stardict entry past the end of the .dict file")
+		}
+		defs = append(defs, d.entryText(d.dict[e.off:e.off+e.size]))
+	}
+	return defs, nil
+}
+
+// entryText gets the text out of an entry; with no sametypesequence each field starts with its type,
+// and those in lower case end with a 0.
+func (d *starDict) entryText(data []byte) string {
+	var fields []string
+	if len(d.typeSeq) == 1 {
+		return textOf(d.typeSeq[0], data)
+	}
+	types := d.typeSeq
+	for len(data) > 0 {
+		var t byte
+		if types != "" {
+			t, types = types[0], types[1:]
+		} else {
+			t, data = data[0], data[1:]
+		}
+		if t >= 'A' && t <= 'Z' { // binary data, with its size first
+			if len(data) < 4 {
+				break
+			}
+			n := int(binary.BigEndian.Uint32(data))
+			if n > len(data)-4 {
+				break
+			}
+			data = data[4+n:]
+			continue
+		}
+		end := bytes.IndexByte(data, 0)
+		if end < 0 {
+			end = len(data)
+		}
+		fields = append(fields, textOf(t, data[:end]))
+		if end < len(data) {
+			end++
+		}
+		data = data[end:]
+	}
+	return strings.Join(fields, "\n")
+}
+
+var tagRE = regexp.MustCompile(`<[^>]*>`)
+var breakRE = regexp.MustCompile(`(?i)<br\s*/?>|</p>|</div>|</li>`)
+
+// textOf is the plain text of a StarDict field: m, t, y, ... are plain; g, h, x are markup.
+func textOf(t byte, data []byte) string {
+	s := string(data)
+	if t == 'g' || t == 'h' || t == 'x' {
+		s = breakRE.ReplaceAllString(s, "\n")
+		s = tagRE.ReplaceAllString(s, "")
+		s = strings.NewReplacer("&lt;", "<", "&gt;", ">", "&amp;", "&", "&quot;", `"`, "&nbsp;", " ").Replace(s)
+	}
+	return s
+}
+
+// dictdDict is a dictd dictionary: word.index ("word<TAB>offset<TAB>length", in base64) and word.dict[.dz].
+type dictdDict struct {
+	index map[string][]dictEntry
+	dict  []byte
+}
+
+func openDictd(indexFname string) (*dictdDict, error) {
+	f, err := os.Open(indexFname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	d := &dictdDict{index: map[string][]dictEntry{}}
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fs := strings.Split(sc.Text(), "\t")
+		if len(fs) < 3 || strings.HasPrefix(fs[0], "00-database") {
+			continue
+		}
+		d.index[fs[0]] = append(d.index[fs[0]], dictEntry{dictdNum(fs[1]), dictdNum(fs[2])})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if d.dict, err = readMaybeDz(strings.TrimSuffix(indexFname, ".index") + ".dict"); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// dictdNum decodes dictd's base64 numbers.
+func dictdNum(s string) int64 {
+	const digits = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+	var n int64
+	for _, c := range s {
+		n = n*64 + int64(strings.IndexRune(digits, c))
+	}
+	return n
+}
+
+func (d *dictdDict) define(word string) ([]string, error) {
+	var defs []string
+	for _, e := range d.index[word] {
+		if e.off+e.size > int64(len(d.dict)) {
+			return defs, errors.New("dictd entry past the end of the .dict file")
+		}
+		defs = append(defs, string(d.dict[e.off:e.off+e.size]))
+	}
+	return defs, nil
+}
+
+// wiktDict is a kaikki.org (wiktextract) jsonl dump, looked up through an index of where each word's
+// lines are (made by `forvosay import`, or the first time it's needed), so it isn't all read in.
+type wiktDict struct {
+	fname string
+	once  sync.Once
+	index map[string][]dictEntry
+	err   error
+}
+
+type wiktEntry struct {
+	Word   string
+	Pos    string
+	Senses []struct {
+		Glosses []string
+	}
+}
+
+func openWiktDict(fname string) (*wiktDict, error) {
+	if strings.HasSuffix(fname, ".gz") {
+		return nil, fmt.Errorf("%s: can't look things up in a gzipped jsonl file; gunzip it", fname)
+	}
+	return &wiktDict{fname: fname}, nil
+}
+
+// wiktIndexFname names the index of a jsonl dump, by its absolute path.
+func wiktIndexFname(fname string) string {
+	if abs, err := filepath.Abs(fname); err == nil {
+		fname = abs
+	}
+	return cacheDir + "/.define/" + url.PathEscape(fname) + ".tsv"
+}
+
+// indexWikt writes the index of a jsonl dump: "word<TAB>offset<TAB>length" lines.
+func indexWikt(fname string) (int, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	var b strings.Builder
+	n := 0
+	br := bufio.NewReader(f)
+	for off := int64(0); ; {
+		line, err := br.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var e struct{ Word string }
+			if jerr := json.Unmarshal(line, &e); jerr != nil {
+				return n, fmt.Errorf("%s: %v", fname, jerr)
+			}
+			if e.Word != "" && !strings.ContainsAny(e.Word, "\t\n") {
+				fmt.Fprintf(&b, "%s\t%d\t%d\n", e.Word, off, len(line))
+				n++
+			}
+		}
+		off += int64(len(line))
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return n, err
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(wiktIndexFname(fname)), 0777); err != nil {
+		return n, err
+	}
+	return n, ioutil.WriteFile(wiktIndexFname(fname), []byte(b.String()), 0666)
+}
+
+// load reads the index, making it first if it's missing or older than the dump.
+func (d *wiktDict) load() {
+	d.index = map[string][]dictEntry{}
+	fi, err := os.Stat(d.fname)
+	if err != nil {
+		d.err = err
+		return
+	}
+	if ifi, err := os.Stat(wiktIndexFname(d.fname)); err != nil || ifi.ModTime().Before(fi.ModTime()) {
+		msgf("indexing %s...\n", d.fname)
+		if _, err := indexWikt(d.fname); err != nil {
+			d.err = err
+			return
+		}
+	}
+	f, err := os.Open(wiktIndexFname(d.fname))
+	if err != nil {
+		d.err = err
+		return
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fs := strings.Split(sc.Text(), "\t")
+		if len(fs) != 3 {
+			continue
+		}
+		off, err1 := strconv.ParseInt(fs[1], 10, 64)
+		size, err2 := strconv.ParseInt(fs[2], 10, 64)
+		if err1 == nil && err2 == nil {
+			d.index[fs[0]] = append(d.index[fs[0]], dictEntry{off, size})
+		}
+	}
+	d.err = sc.Err()
+}
+
+func (d *wiktDict) define(word string) ([]string, error) {
+	d.once.Do(d.load)
+	if d.err != nil || len(d.index[word]) == 0 {
+		return nil, d.err
+	}
+	f, err := os.Open(d.fname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var defs []string
+	for _, e := range d.index[word] {
+		buf := make([]byte, e.size)
+		if _, err := f.ReadAt(buf, e.off); err != nil {
+			return defs, fmt.Errorf("%s: %v (has it changed since it was indexed?)", d.fname, err)
+		}
+		var we wiktEntry
+		if err := json.Unmarshal(buf, &we); err != nil || we.Word != word {
+			return defs, fmt.Errorf("%s: index is out of date", d.fname)
+		}
+		var b strings.Builder
+		for i, s := range we.Senses {
+			if len(s.Glosses) > 0 {
+				fmt.Fprintf(&b, "%d. %s\n", i+1, s.Glosses[len(s.Glosses)-1])
+			}
+		}
+		if b.Len() > 0 {
+			defs = append(defs, we.Pos+"\n"+b.String())
+		}
+	}
+	return defs, nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDictionaries(t *testing.T) {
+	dir, err := ioutil.TempDir("", "forvosay-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	write := func(name string, data []byte) string {
+		fname := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(fname, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return fname
+	}
+
+	// stardict: two entries, the second in html
+	var dict, idx []byte
+	for _, e := range []struct{ word, def string }{
+		{"chat", "cat\na small furry animal"},
+		{"chasser", "to chase<br>to pursue"},
+	} {
+		idx = append(idx, e.word...)
+		idx = append(idx, 0)
+		var n [8]byte
+		binary.BigEndian.PutUint32(n[:4], uint32(len(dict)))
+		binary.BigEndian.PutUint32(n[4:], uint32(len(e.def)))
+		idx = append(idx, n[:]...)
+		dict = append(dict, e.def...)
+	}
+	write("fr.idx", idx)
+	write("fr.dict", dict)
+	ifo := write("fr.ifo", []byte("StarDict's dict ifo file\nversion=2.4.2\nwordcount=2\nsametypesequence=h\n"))
+
+	// dictd: offsets and lengths in base64
+	write("de.dict", []byte("Hund\n  dog\nKatze\n  cat\n"))
+	index := write("de.index", []byte("00-database-short\tA\tB\nHund\tA\tL\nKatze\tL\tM\n"))
+
+	old := *defineFiles
+	*defineFiles = "fr=" + ifo + ",de=" + index
+	resetDictionaries()
+	defer func() {
+		*defineFiles = old
+		resetDictionaries()
+	}()
+	for _, tc := range []struct {
+		word, lang string
+		want       []string
+	}{
+		{"chat", "fr", []string{"cat\na small furry animal"}},
+		{"chasser", "fr", []string{"to chase\nto pursue"}},
+		{"Hund", "de", []string{"Hund\n  dog\n"}},
+		{"Katze", "de", []string{"Katze\n  cat\n"}},
+		{"Katze", "fr", nil},
+	} {
+		if got := definitions(tc.word, tc.lang); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("definitions(%q, %q) = %q, want %q", tc.word, tc.lang, got, tc.want)
+		}
+	}
+	if got, want := conciseDefs([]string{"a\n\n  b  c\nd\ne"}), []string{"a", "b c", "d"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("conciseDefs = %q, want %q", got, want)
+	}
+	for _, tc := range []struct {
+		word  string
+		langs []string
+		want  []string
+	}{
+		{"Hund,", []string{"fr", "de"}, []string{"Hund\n  dog\n"}},
+		{"CHAT", []string{"fr"}, []string{"cat\na small furry animal"}},
+		{"hund", []string{"de"}, nil},
+	} {
+		if got := printDefinitions(tc.word, tc.langs, false); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("printDefinitions(%q, %q) = %q, want %q", tc.word, tc.langs, got, tc.want)
+		}
+	}
+}
+
+func TestWiktDict(t *testing.T) {
+	defer useFixtures(t)()
+	dir, err := ioutil.TempDir("", "forvosay-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fname := filepath.Join(dir, "kaikki.jsonl")
+	lines := `{"word":"Hund","pos":"noun","senses":[{"glosses":["dog"]},{"glosses":["animal","hound"]}]}` + "\n\n" +
+		`{"word":"Katze","pos":"noun","senses":[{"glosses":["cat"]}]}` + "\n" +
+		`{"word":"Hund","pos":"name","senses":[{"glosses":["a surname"]}]}`
+	if err := ioutil.WriteFile(fname, []byte(lines), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := indexWikt(fname); err != nil || n != 3 {
+		t.Fatalf("indexWikt = %d %v, want 3 entries", n, err)
+	}
+	d, err := openWiktDict(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		word string
+		want []string
+	}{
+		{"Hund", []string{"noun\n1. dog\n2. hound\n", "name\n1. a surname\n"}},
+		{"Katze", []string{"noun\n1. cat\n"}},
+		{"Maus", nil},
+	} {
+		if got, err := d.define(tc.word); err != nil || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("define(%q) = %q %v, want %q", tc.word, got, err, tc.want)
+		}
+	}
+	if _, err := openWiktDict(fname + ".gz"); err == nil {
+		t.Error("opened a gzipped dump")
+	}
+
+	// with no index, it's made when first needed
+	os.Remove(wiktIndexFname(fname))
+	d, _ = openWiktDict(fname)
+	if got, err := d.define("Katze"); err != nil || len(got) != 1 {
+		t.Errorf("without an index, define(Katze) = %q %v", got, err)
+	}
+}
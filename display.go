@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+var format = flag.String("format", "{{.Fname}} of {{.Of}}: {{.Username}} ({{.Sex}}, {{.Country}}), {{.NumPositiveVotes}}/{{.NumVotes}} votes", "`template` (Go text/template) for the line printed when playing a pronunciation; fields are those of Pronunciation plus Fname, Num, Of, and IPA and Roman (from forvosay import)")
+var listAll = flag.Bool("list", false, "list every available pronunciation of the word, numbered, before playing")
+var pick = flag.Int("pick", 0, "play pronunciation number `N` (as numbered by -list) instead of a random one")
+
+var formatTmpl *template.Template
+
+// playInfo is what the -format template is executed against.
+type playInfo struct {
+	Pronunciation
+	Fname string // cached mp3 file
+	Num   int    // 1-based number, as shown by -list and accepted by -pick
+	Of    int    // total number of pronunciations for the word (from any -country)
+	IPA   string // from the files indexed by `forvosay import`, if any
+	Roman string
+}
+
+func parseFormat() error {
+	t, err := template.New("format").Parse(*format)
+	if err != nil {
+		return fmt.Errorf("bad -format: %v", err)
+	}
+	formatTmpl = t
+	return nil
+}
+
+func formatItem(req Req, item Pronunciation, of int, tmpl *template.Template) string {
+	return formatPlay(item, req.CacheMP3Fname(item.Index), of, pronOf(req), tmpl)
+}
+
+func formatPlay(item Pronunciation, fname string, of int, pron pronInfo, tmpl *template.Template) string {
+	var b strings.Builder
+	info := playInfo{item, fname, item.Index + 1, of, pron.IPA, pron.Roman}
+	if err := tmpl.Execute(&b, info); err != nil {
+		return fmt.Sprint(info.Fname, " of ", of, " (bad -format: ", err, ")")
+	}
+	return b.String()
+}
+
+// listItems lists resp's pronunciations, numbered as forvo has them, of the total it has.
+func listItems(req Req, resp Resp, of int, tmpl *template.Template) {
+	for _, item := range resp.Items {
+		msgf("%2d. %s\n", item.Index+1, formatItem(req, item, of, tmpl))
+	}
+}
+
+// onlyPick returns just pronunciation number num (1-based), or no items at all if there's no such pronunciation.
+func onlyPick(resp Resp, num int) Resp {
+	resp2 := Resp{}
+	for _, item := range resp.Items {
+		if item.Index+1 == num {
+			resp2.Items = append(resp2.Items, item)
+		}
+	}
+	return resp2
+}
@@ -3,11 +3,11 @@ package main
 import (
 	"fmt"
 	"io/ioutil"
-	"net/http"
 	"os"
 )
 
 type MaybeMP3 struct {
+	Item  Pronunciation
 	Fname string
 	Err   error
 }
@@ -15,41 +15,41 @@ type MaybeMP3 struct {
 func CacheMP3s(req Req, resp Resp, cb func(MaybeMP3)) {
 	ch := make(chan MaybeMP3, len(resp.Items))
 	for _, item := range resp.Items {
-		go cachedMP3(item.PathMP3, req.CacheMP3Fname(item.Index), ch)
+		go cachedMP3(item, req.CacheMP3Fname(item.Index), ch)
 	}
 	for range resp.Items {
 		cb(<-ch)
 	}
 }
 
-func cachedMP3(url, fname string, result chan MaybeMP3) {
+func cachedMP3(item Pronunciation, fname string, result chan MaybeMP3) {
 	if !*refreshCache {
 		if _, err := os.Stat(fname); err == nil {
 			// cached we are done
-			result <- MaybeMP3{fname, nil}
+			result <- MaybeMP3{item, fname, nil}
 			return
 		}
 	}
 	// not cached, download it
-	r, err := http.Get(url)
+	r, err := httpClient.Get(audioURL(item.PathMP3))
 	if err != nil {
-		result <- MaybeMP3{"", err}
+		result <- MaybeMP3{item, "", redactErr(err)}
 		return
 	}
 	defer r.Body.Close()
 	if r.StatusCode < 200 || r.StatusCode >= 300 {
-		result <- MaybeMP3{"", fmt.Errorf("bad download status for MP3 file: %v", r.Status)}
+		result <- MaybeMP3{item, "", fmt.Errorf("bad download status for MP3 file: %v", r.Status)}
 		return
 	}
 	buf, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		result <- MaybeMP3{"", fmt.Errorf("error while downloading MP3: %v", err)}
+		result <- MaybeMP3{item, "", fmt.Errorf("error while downloading MP3: %v", err)}
 		return
 	}
 	if err := ioutil.WriteFile(fname, buf, 0666); err != nil {
-		result <- MaybeMP3{"", fmt.Errorf("error saving MP3: %v", err)}
+		result <- MaybeMP3{item, "", fmt.Errorf("error saving MP3: %v", err)}
 		return
 	}
 	// we're good
-	result <- MaybeMP3{fname, nil}
+	result <- MaybeMP3{item, fname, nil}
 }
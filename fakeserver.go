@@ -0,0 +1,235 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fakeServer serves the bits of the forvo api that we use, from a directory laid out like the cache.
+type fakeServer struct {
+	fixtures   map[string]fixture // recorded with -record, by url path (with the key masked)
+	latency    time.Duration
+	failRate   float64
+	failStatus int
+	limit      int // pronunciation list requests per key, after which we say so like forvo does; 0 for no limit
+
+	mu       sync.Mutex
+	requests map[string]int // by key
+}
+
+func runFakeServer(args []string) {
+	fs := flag.NewFlagSet("fakeserver", flag.ExitOnError)
+	addr := fs.String("addr", "localhost:8765", "listen on this `address`")
+	dir := fs.String("dir", cacheDir, "serve pronunciations from this `dir`ectory, laid out like ~/.forvocache")
+	fixtures := fs.String("fixtures", "", "also serve the responses recorded (with -record) in this `dir`ectory")
+	latency := fs.Duration("latency", 0, "wait this long before answering each request")
+	failRate := fs.Float64("failrate", 0, "fail this `fraction` of requests")
+	failStatus := fs.Int("failstatus", 500, "http `status` to fail requests with")
+	limit := fs.Int("limit", 0, "refuse a key after this many pronunciation list requests, like forvo does when a key has had enough for the day")
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, os.Args[0], ` fakeserver [<options>]
+
+Serve the forvo api from a local cache directory, for use without network
+access or api keys; point forvosay at it with
+	-api http://localhost:8765 -audiohost http://localhost:8765
+
+options:
+`)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		fatal("unknown argument:", fs.Arg(0))
+	}
+	cacheDir = *dir
+	s := &fakeServer{
+		fixtures:   map[string]fixture{},
+		latency:    *latency,
+		failRate:   *failRate,
+		failStatus: *failStatus,
+		limit:      *limit,
+		requests:   map[string]int{},
+	}
+	if *fixtures != "" {
+		if err := s.loadFixtures(*fixtures); err != nil {
+			fatal("could not load fixtures:", err)
+		}
+	}
+	fmt.Println("serving", cacheDir, "on", *addr)
+	fatal(http.ListenAndServe(*addr, s))
+}
+
+func (s *fakeServer) loadFixtures(dir string) error {
+	fnames, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, fname := range fnames {
+		buf, err := ioutil.ReadFile(fname)
+		if err != nil {
+			return err
+		}
+		var f fixture
+		if err := json.Unmarshal(buf, &f); err != nil {
+			return fmt.Errorf("%s: %v", fname, err)
+		}
+		u, err := url.Parse(f.URL)
+		if err != nil {
+			return fmt.Errorf("%s: %v", fname, err)
+		}
+		s.fixtures[u.EscapedPath()] = f
+	}
+	return nil
+}
+
+func (s *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	time.Sleep(s.latency)
+	if s.failRate > 0 && rand.Float64() < s.failRate {
+		http.Error(w, "injected failure", s.failStatus)
+		return
+	}
+	if f, ok := s.fixtures[keyInURL.ReplaceAllString(r.URL.EscapedPath(), "/key/KEY/")]; ok {
+		for k, v := range f.Header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(f.Status)
+		w.Write(f.body())
+		return
+	}
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/key/"):
+		s.serveAPI(w, r)
+	case strings.HasPrefix(r.URL.Path, "/audio/"):
+		s.serveAudio(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// pathParts splits an escaped url path into its (unescaped) parts, so a word can have a / in it.
+func pathParts(p string) []string {
+	parts := strings.Split(strings.Trim(p, "/"), "/")
+	for i, part := range parts {
+		if s, err := url.PathUnescape(part); err == nil {
+			parts[i] = s
+		}
+	}
+	return parts
+}
+
+// apiParams splits an escaped api path like /key/K/format/json/action/A/... into its name/value pairs.
+func apiParams(p string) map[string]string {
+	parts := pathParts(p)
+	params := map[string]string{}
+	for i := 0; i+1 < len(parts); i += 2 {
+		params[parts[i]] = parts[i+1]
+	}
+	return params
+}
+
+func (s *fakeServer) serveAPI(w http.ResponseWriter, r *http.Request) {
+	params := apiParams(r.URL.EscapedPath())
+	if s.limit > 0 && params["action"] == "word-pronunciations" {
+		s.mu.Lock()
+		s.requests[params["key"]]++
+		n := s.requests[params["key"]]
+		s.mu.Unlock()
+		if n > s.limit {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`["Limit/day reached."]`))
+			return
+		}
+	}
+	switch params["action"] {
+	case "word-pronunciations":
+		s.serveWord(w, r, Req{params["word"], params["language"]}, false)
+	case "standard-pronunciation":
+		s.serveWord(w, r, Req{params["word"], params["language"]}, true)
+	case "words-search", "pronounced-words-search":
+		s.serveSearch(w, params["language"], params["search"], 0)
+	case "popular-pronounced-words":
+		limit, _ := strconv.Atoi(params["limit"])
+		s.serveSearch(w, params["language"], "", limit)
+	case "language-list":
+		var langs []Language
+		for _, l := range cachedLangs() {
+			langs = append(langs, Language{l, l})
+		}
+		s.serveItems(w, langs)
+	default:
+		http.Error(w, `["Action not supported by fakeserver."]`, http.StatusBadRequest)
+	}
+}
+
+// serveWord answers with the cached pronunciations that have a cached mp3 too (or just the first, for standard).
+func (s *fakeServer) serveWord(w http.ResponseWriter, r *http.Request, req Req, standard bool) {
+	resp, err := getCachedResp(req)
+	if err != nil {
+		resp = &Resp{}
+	}
+	served := Resp{Items: []Pronunciation{}}
+	for _, item := range resp.Items {
+		if _, err := os.Stat(req.CacheMP3Fname(item.Index)); err != nil {
+			continue
+		}
+		item.PathMP3 = fmt.Sprintf("http://%s/audio/%s/%s/%d", r.Host, url.PathEscape(req.LangCode), url.PathEscape(req.Word), item.Index)
+		item.PathOGG = ""
+		served.Items = append(served.Items, item)
+		if standard {
+			break
+		}
+	}
+	s.serveItems(w, served.Items)
+}
+
+// serveSearch answers with the cached words starting with prefix, most pronounced first.
+func (s *fakeServer) serveSearch(w http.ResponseWriter, lang, prefix string, limit int) {
+	words := []WordInfo{}
+	for _, word := range cachedWords(lang) {
+		if !strings.HasPrefix(word, prefix) {
+			continue
+		}
+		resp, err := getCachedResp(Req{word, lang})
+		if err != nil || len(resp.Items) == 0 {
+			continue
+		}
+		words = append(words, WordInfo{Word: word, Original: resp.Items[0].Original, NumPronunciations: len(resp.Items)})
+	}
+	sort.SliceStable(words, func(i, j int) bool { return words[i].NumPronunciations > words[j].NumPronunciations })
+	if limit > 0 && len(words) > limit {
+		words = words[:limit]
+	}
+	s.serveItems(w, words)
+}
+
+func (s *fakeServer) serveItems(w http.ResponseWriter, items interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct{ Items interface{} }{items})
+}
+
+func (s *fakeServer) serveAudio(w http.ResponseWriter, r *http.Request) {
+	parts := pathParts(strings.TrimPrefix(r.URL.EscapedPath(), "/audio/"))
+	if len(parts) != 3 {
+		http.NotFound(w, r)
+		return
+	}
+	index, err := strconv.Atoi(parts[2])
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "audio/mpeg")
+	http.ServeFile(w, r, Req{parts[1], parts[0]}.CacheMP3Fname(index))
+}
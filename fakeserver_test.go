@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// useFakeServer points the client at a fakeserver serving a temp cache dir with one word (with a / in
+// it) in it, returning the server and a func to put things back.
+func useFakeServer(t *testing.T) (*fakeServer, func()) {
+	oldAPI, oldAudio, oldKeys, oldCacheDir := *apiBase, *audioHost, apiKeys, cacheDir
+	dir, err := ioutil.TempDir("", "forvosay-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cacheDir = dir
+	req := Req{"km/h", "de"}
+	if err := saveRespToCache(req, Resp{[]Pronunciation{{Id: 1, Word: "km/h", Original: "km/h", Username: "x", Code: "de"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(req.CacheMP3Fname(0), []byte("fake mp3"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeServer{fixtures: map[string]fixture{}, requests: map[string]int{}}
+	srv := httptest.NewServer(s)
+	*apiBase, *audioHost = srv.URL, srv.URL
+	apiKeys = []string{"key1", "key2"}
+	useTransport()
+	return s, func() {
+		srv.Close()
+		*apiBase, *audioHost, apiKeys, cacheDir = oldAPI, oldAudio, oldKeys, oldCacheDir
+		useTransport()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestFakeServerWord(t *testing.T) {
+	_, restore := useFakeServer(t)
+	defer restore()
+	resp, err := Get(Req{"km/h", "de"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Items) != 1 || resp.Items[0].Word != "km/h" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	r, err := httpClient.Get(audioURL(resp.Items[0].PathMP3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Body.Close()
+	buf, _ := ioutil.ReadAll(r.Body)
+	if r.StatusCode != 200 || !bytes.Equal(buf, []byte("fake mp3")) {
+		t.Errorf("audio: %v %q", r.Status, buf)
+	}
+}
+
+func TestFakeServerSearch(t *testing.T) {
+	_, restore := useFakeServer(t)
+	defer restore()
+	words, err := SearchWords("de", "km", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(words) != 1 || words[0].Original != "km/h" || words[0].NumPronunciations != 1 {
+		t.Errorf("unexpected words: %+v", words)
+	}
+}
+
+func TestFakeServerLimit(t *testing.T) {
+	s, restore := useFakeServer(t)
+	defer restore()
+	s.limit = 1
+	for i := 0; i < 2; i++ {
+		if _, err := SearchWords("de", "km", false); err != nil {
+			t.Fatalf("search %d: %v", i+1, err)
+		}
+	}
+	if u := loadUsage(); u.key("key1").Refused {
+		t.Error("searches counted against -limit")
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := Get(Req{"km/h", "de"}); err != nil {
+			t.Fatalf("request %d: %v", i+1, err)
+		}
+	}
+	u := loadUsage()
+	if !u.key("key1").Refused || u.key("key2").Refused {
+		t.Errorf("expected just key1 to be refused: %+v", u.Keys)
+	}
+	if _, err := Get(Req{"km/h", "de"}); err != errQuota {
+		t.Errorf("with both keys refused, got %v, want errQuota", err)
+	}
+}
+
+func TestSearchMaxAge(t *testing.T) {
+	_, restore := useFakeServer(t)
+	defer restore()
+	search := func() int {
+		words, err := SearchWords("de", "km", true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(words)
+	}
+	search()
+	if err := saveRespToCache(Req{"kmz", "de"}, Resp{[]Pronunciation{{Id: 2, Word: "kmz"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if n := search(); n != 1 {
+		t.Errorf("search wasn't cached: %d words", n)
+	}
+	old := time.Now().Add(-searchMaxAge - time.Hour)
+	filepath.Walk(filepath.Join(cacheDir, ".api"), func(fname string, fi os.FileInfo, err error) error {
+		if err == nil && !fi.IsDir() {
+			err = os.Chtimes(fname, old, old)
+		}
+		return err
+	})
+	if n := search(); n != 2 {
+		t.Errorf("old cached search was used: %d words, want 2", n)
+	}
+}
@@ -3,15 +3,9 @@ package main
 import (
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"net/http"
 	"net/url"
-	"os"
-	"time"
 )
 
-var apiKey = os.Getenv("FORVO_API_KEY")
-
 type Pronunciation struct {
 	Id               int64
 	Word             string
@@ -51,44 +45,33 @@ func (req Req) CacheMP3Fname(index int) string {
 	return fmt.Sprintf("%s/%s-%02d.mp3", req.CacheDir(), sanitizeFname(req.Word), index+1)
 }
 
-func Get(req Req) (*Resp, error) {
-	var fullAddr string
-	if *bench {
-		t0 := time.Now()
-		defer func() {
-			fmt.Println(fullAddr)
-			fmt.Println(time.Since(t0))
-		}()
+// apiWord is word, escaped for an api url.
+func apiWord(word string) string {
+	if word == "0" {
+		word = " 0" // workaround apparent forvo bug
 	}
-	if req.Word == "0" {
-		req.Word = " 0" // workaround apparent forvo bug
-	}
-	addr := "https://apifree.forvo.com"
-	if *nossl {
-		addr = "http://apifree.forvo.com"
+	return url.PathEscape(word)
+}
+
+// wordParams are the api parameters for the pronunciations of req.
+func wordParams(req Req) []string {
+	return []string{
+		"word", apiWord(req.Word),
+		"language", req.LangCode,
+		"order", "rate-desc",
 	}
-	fullAddr = fmt.Sprint(
-		addr,
-		"/key/", apiKey,
-		"/format/json",
-		"/action/word-pronunciations",
-		"/word/", url.PathEscape(req.Word),
-		"/language/", req.LangCode,
-		"/order/rate-desc",
-	)
-	fmt.Println("downloading pronunciation list...")
-	resp, err := http.Get(fullAddr)
+}
+
+// wordURL is the api url for the pronunciations of req. It contains the key, so don't print it without redact.
+func wordURL(key string, req Req) string {
+	return apiURL(key, "word-pronunciations", wordParams(req)...)
+}
+
+func Get(req Req) (*Resp, error) {
+	buf, err := apiGet("word-pronunciations", wordParams(req)...)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-	buf, err := ioutil.ReadAll(resp.Body)
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("forvo complained: HTPP %s (%s)", resp.Status, string(buf))
-	}
-	if err != nil {
-		return nil, fmt.Errorf("error reading forvo response body: %s", err)
-	}
 	var pr Resp
 	if err := json.Unmarshal(buf, &pr); err != nil {
 		return nil, fmt.Errorf("forvo response «%s» could not be unmarshalled as json: %v", err, string(buf))
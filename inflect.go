@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+var inflect = flag.Bool("inflect", false, "if a word has no pronunciations, try it without articles and inflections (der Hund -> Hund, l'homme -> homme, perros -> perro); each form tried can cost an api request")
+var lemmaFile = flag.String("lemmas", "", "with -inflect, also try the lemma of words per this `file` of lemma<TAB>form lines")
+
+// formRules says how to get from a form copied out of a text to the form forvo is likely to have.
+type formRules struct {
+	articles []string              // words stripped from the front of a phrase
+	elisions []string              // prefixes stripped from the front of a word
+	endings  [][2]string           // suffix, replacement; tried longest suffix first, otherwise in order
+	extra    func(string) []string // more forms to try, before the endings
+}
+
+var rules = map[string]formRules{
+	"de": {
+		articles: strings.Fields("der die das den dem des ein eine einen einem einer eines"),
+		endings:  [][2]string{{"innen", "in"}, {"en", ""}, {"er", ""}, {"e", ""}, {"n", ""}, {"s", ""}},
+		extra:    unUmlaut,
+	},
+	"en": {
+		articles: strings.Fields("the a an to"),
+		endings:  [][2]string{{"ies", "y"}, {"es", ""}, {"s", ""}, {"ied", "y"}, {"ed", ""}, {"ing", ""}},
+	},
+	"es": {
+		articles: strings.Fields("el la los las un una unos unas lo"),
+		endings:  [][2]string{{"ces", "z"}, {"es", ""}, {"s", ""}},
+	},
+	"fr": {
+		articles: strings.Fields("le la les un une des du de"),
+		elisions: strings.Fields("l' d' qu' j' m' t' s' n' c'"),
+		endings:  [][2]string{{"eaux", "eau"}, {"aux", "al"}, {"s", ""}, {"x", ""}},
+	},
+	"it": {
+		articles: strings.Fields("il lo la i gli le un uno una"),
+		elisions: strings.Fields("l' un' dell' all' nell' sull' dall' quest'"),
+		endings:  [][2]string{{"chi", "co"}, {"ghi", "go"}, {"che", "ca"}, {"ghe", "ga"}, {"i", "o"}, {"i", "e"}, {"e", "a"}},
+	},
+	"pt": {
+		articles: strings.Fields("o a os as um uma uns umas"),
+		endings:  [][2]string{{"ões", "ão"}, {"ães", "ão"}, {"ais", "al"}, {"éis", "el"}, {"ns", "m"}, {"es", ""}, {"s", ""}},
+	},
+	"ca": {
+		articles: strings.Fields("el la els les un una uns unes"),
+		elisions: strings.Fields("l' d'"),
+		endings:  [][2]string{{"es", "a"}, {"s", ""}},
+	},
+	"nl": {
+		articles: strings.Fields("de het een 't"),
+		endings:  [][2]string{{"'s", ""}, {"en", ""}, {"s", ""}},
+	},
+}
+
+// unUmlaut undoes the umlaut plurals of German: Bäume -> Baum.
+func unUmlaut(w string) []string {
+	var cands []string
+	for _, e := range []string{"e", "er", ""} {
+		stem := strings.TrimSuffix(w, e)
+		if e != "" && stem == w {
+			continue
+		}
+		if i := strings.LastIndexAny(stem, "äöü"); i >= 0 {
+			r, n := utf8.DecodeRuneInString(stem[i:])
+			plain := map[rune]string{'ä': "a", 'ö': "o", 'ü': "u"}[r]
+			cands = append(cands, stem[:i]+plain+stem[i+n:])
+		}
+	}
+	return cands
+}
+
+var lemmas struct {
+	once   sync.Once
+	pairs  [][2]string // lemma, form; as in the file
+	mu     sync.Mutex
+	byLang map[string]map[string]string // form -> lemma, normalized for the language
+}
+
+// resetLemmas has the -lemmas file read again when next needed.
+func resetLemmas() {
+	lemmas.mu.Lock()
+	defer lemmas.mu.Unlock()
+	lemmas.once = sync.Once{}
+	lemmas.pairs, lemmas.byLang = nil, nil
+}
+
+func loadLemmas() {
+	optionsMu.RLock()
+	fname := *lemmaFile
+	optionsMu.RUnlock()
+	if fname == "" {
+		return
+	}
+	f, err := os.Open(fname)
+	if err != nil {
+		msg("warning: could not read lemma list:", err)
+		return
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		parts := strings.Split(sc.Text(), "\t")
+		if len(parts) < 2 {
+			continue
+		}
+		lemmas.pairs = append(lemmas.pairs, [2]string{parts[0], parts[1]})
+	}
+	if err := sc.Err(); err != nil {
+		msg("warning: could not read lemma list:", err)
+	}
+}
+
+func lemma(word, lang string) (string, bool) {
+	lemmas.mu.Lock()
+	defer lemmas.mu.Unlock()
+	lemmas.once.Do(loadLemmas)
+	if lemmas.byLang == nil {
+		lemmas.byLang = map[string]map[string]string{}
+	}
+	forms, ok := lemmas.byLang[lang]
+	if !ok {
+		forms = map[string]string{}
+		for _, p := range lemmas.pairs {
+			lemma, form := normalizeWord(p[0], lang), normalizeWord(p[1], lang)
+			if _, ok := forms[form]; !ok && lemma != form {
+				forms[form] = lemma
+			}
+		}
+		lemmas.byLang[lang] = forms
+	}
+	l, ok := forms[word]
+	return l, ok
+}
+
+// formCandidates lists the forms to try, in order, when (normalized) word has no pronunciations;
+// the word itself isn't included.
+func formCandidates(word, lang string) []string {
+	r := rules[baseLang(lang)]
+	var cands []string
+	seen := map[string]bool{word: true}
+	add := func(c string) {
+		if c != "" && !seen[c] {
+			seen[c] = true
+			cands = append(cands, c)
+		}
+	}
+	bare := word
+	if fs := strings.Fields(bare); len(fs) > 1 {
+		for _, a := range r.articles {
+			if fs[0] == a {
+				bare = strings.Join(fs[1:], " ")
+				break
+			}
+		}
+	}
+	for _, e := range r.elisions {
+		if strings.HasPrefix(bare, e) && len(bare) > len(e) {
+			bare = bare[len(e):]
+			break
+		}
+	}
+	add(bare)
+	if l, ok := lemma(bare, lang); ok {
+		add(l)
+	}
+	if l, ok := lemma(word, lang); ok {
+		add(l)
+	}
+	if strings.Contains(bare, " ") {
+		return cands // only single words get their endings stripped
+	}
+	if r.extra != nil {
+		for _, c := range r.extra(bare) {
+			add(c)
+		}
+	}
+	endings := append([][2]string{}, r.endings...)
+	sort.SliceStable(endings, func(i, j int) bool {
+		return utf8.RuneCountInString(endings[i][0]) > utf8.RuneCountInString(endings[j][0])
+	})
+	var matched []string
+endings:
+	for _, e := range endings {
+		for _, m := range matched {
+			if len(e[0]) < len(m) && strings.HasSuffix(m, e[0]) {
+				continue endings // part of a longer ending already stripped: bateaux isn't bateal
+			}
+		}
+		if stem := strings.TrimSuffix(bare, e[0]); stem != bare && utf8.RuneCountInString(stem) >= 2 {
+			matched = append(matched, e[0])
+			add(stem + e[1])
+		}
+	}
+	return cands
+}
+
+// resolve looks up the pronunciations of word, and if there are none, of its traditional/simplified
+// form and (if tryForms) of the forms from formCandidates, saying which one it ended up with.
+func resolve(word, lang string, tryForms bool) (Req, *Resp, error) {
+	req := Req{word, lang}
+	resp, err := CacheResp(req)
+	if err != nil || len(resp.Items) > 0 {
+		return req, resp, err
+	}
+	if req2, resp2, ok := resolveVariant(req); ok {
+		return req2, resp2, nil
+	}
+	if !tryForms {
+		return req, resp, nil
+	}
+	for _, c := range formCandidates(word, lang) {
+		req2 := Req{c, lang}
+		resp2, err := CacheResp(req2)
+		if err != nil {
+			msgf("could not try `%v` instead: %v\n", c, err)
+			break
+		}
+		if len(resp2.Items) > 0 {
+			msgf("no pronunciations of `%v`; using `%v`\n", word, c)
+			return req2, resp2, nil
+		}
+	}
+	return req, resp, nil
+}
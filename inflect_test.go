@@ -0,0 +1,29 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFormCandidates(t *testing.T) {
+	for _, tc := range []struct {
+		word, lang string
+		want       []string
+	}{
+		{"der hund", "de", []string{"hund"}},
+		{"bäume", "de", []string{"baum", "baume", "bäum"}},
+		{"lehrerinnen", "de", []string{"lehrerin"}},
+		{"l'homme", "fr", []string{"homme"}},
+		{"chevaux", "fr", []string{"cheval"}},
+		{"bateaux", "fr", []string{"bateau"}},
+		{"los perros", "es", []string{"perros", "perro"}},
+		{"luces", "es", []string{"luz"}},
+		{"amiche", "it", []string{"amica"}},
+		{"amici", "it", []string{"amico", "amice"}},
+		{"hund", "xx", nil},
+	} {
+		if got := formCandidates(tc.word, tc.lang); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("formCandidates(%q, %q) = %q, want %q", tc.word, tc.lang, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"crypto/sha256"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+var keyFile = flag.String("keyfile", os.Getenv("HOME")+"/.forvokey", "read forvo api keys from this `file` (one per line), in addition to FORVO_API_KEY; must not be readable by group or others")
+
+// apiKeys are tried in order; when one runs out for the day (or is refused) we move on to the next.
+var apiKeys []string
+
+// loadKeys reads the api keys from FORVO_API_KEY (which may hold several, comma or space separated) and -keyfile.
+func loadKeys() error {
+	add := func(s string) {
+		for _, k := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }) {
+			if !hasKey(k) {
+				apiKeys = append(apiKeys, k)
+			}
+		}
+	}
+	add(os.Getenv("FORVO_API_KEY"))
+	fi, err := os.Stat(*keyFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if fi.Mode().Perm()&0077 != 0 {
+		return fmt.Errorf("key file %s is readable by others (mode %v); chmod 600 it", *keyFile, fi.Mode().Perm())
+	}
+	buf, err := ioutil.ReadFile(*keyFile)
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.Split(string(buf), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			add(line)
+		}
+	}
+	return nil
+}
+
+func hasKey(k string) bool {
+	for _, k2 := range apiKeys {
+		if k == k2 {
+			return true
+		}
+	}
+	return false
+}
+
+// keyID identifies a key (e.g. in the usage file) without giving it away.
+func keyID(key string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(key)))[:8]
+}
+
+// keyRefused reports whether a forvo error response means the key is out of requests for today or isn't accepted at all.
+func keyRefused(status int, body string) bool {
+	switch status {
+	case 401, 403, 429:
+		return true
+	}
+	// forvo says so in the body, rather than with the status
+	return strings.Contains(strings.ToLower(body), "limit/day reached")
+}
@@ -0,0 +1,21 @@
+package main
+
+import "testing"
+
+func TestKeyRefused(t *testing.T) {
+	for _, tc := range []struct {
+		status int
+		body   string
+		want   bool
+	}{
+		{401, "", true},
+		{429, "", true},
+		{400, `["Limit/day reached."]`, true},
+		{500, "<html>internal error: bad api key handling</html>", false},
+		{502, "rate limit of upstream proxy", false},
+	} {
+		if got := keyRefused(tc.status, tc.body); got != tc.want {
+			t.Errorf("keyRefused(%d, %q) = %v, want %v", tc.status, tc.body, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+var compare = flag.Bool("compare", false, "with several -lang codes, play pronunciations from each language rather than just the first that has any")
+
+// langChain is the -lang codes, in the order to try them.
+func langChain() []string {
+	var langs []string
+	for _, l := range strings.Split(*lang, ",") {
+		if l = strings.TrimSpace(l); l != "" {
+			langs = append(langs, l)
+		}
+	}
+	return langs
+}
+
+func firstLang() string {
+	if langs := langChain(); len(langs) > 0 {
+		return langs[0]
+	}
+	return ""
+}
+
+// langAliases are names and ISO 639-2/3 codes people might use instead of forvo's codes; English names
+// are also looked up in forvo's language list.
+var langAliases = map[string]string{
+	"ara": "ar", "arabic": "ar", "العربية": "ar",
+	"cantonese": "yue", "廣東話": "yue", "广东话": "yue", "粵語": "yue", "粤语": "yue",
+	"cat": "ca", "catalan": "ca", "català": "ca",
+	"ces": "cs", "cze": "cs", "czech": "cs", "čeština": "cs",
+	"chi": "zh", "chinese": "zh", "cmn": "zh", "mandarin": "zh", "zho": "zh", "中文": "zh", "普通话": "zh", "國語": "zh",
+	"deu": "de", "ger": "de", "german": "de", "deutsch": "de",
+	"dut": "nl", "nld": "nl", "dutch": "nl", "nederlands": "nl",
+	"ell": "el", "gre": "el", "greek": "el", "ελληνικά": "el",
+	"eng": "en", "english": "en",
+	"fra": "fr", "fre": "fr", "french": "fr", "français": "fr",
+	"glg": "gl", "galician": "gl", "galego": "gl",
+	"heb": "he", "hebrew": "he", "עברית": "he",
+	"hin": "hi", "hindi": "hi", "हिन्दी": "hi",
+	"ita": "it", "italian": "it", "italiano": "it",
+	"jpn": "ja", "japanese": "ja", "日本語": "ja",
+	"kor": "ko", "korean": "ko", "한국어": "ko",
+	"pol": "pl", "polish": "pl", "polski": "pl",
+	"por": "pt", "portuguese": "pt", "português": "pt",
+	"rus": "ru", "russian": "ru", "русский": "ru",
+	"spa": "es", "spanish": "es", "español": "es", "castellano": "es",
+	"swe": "sv", "swedish": "sv", "svenska": "sv",
+	"tha": "th", "thai": "th", "ไทย": "th",
+	"tur": "tr", "turkish": "tr", "türkçe": "tr",
+	"ukr": "uk", "ukrainian": "uk", "українська": "uk",
+	"vie": "vi", "vietnamese": "vi", "tiếng việt": "vi",
+}
+
+// canonLang turns whatever the user called a language into forvo's code for it, checking it against
+// forvo's language list (which is cached after the first time).
+func canonLang(l string, known []Language) (string, error) {
+	l = strings.ToLower(strings.TrimSpace(l))
+	if code, ok := langAliases[l]; ok {
+		l = code
+	}
+	if known == nil {
+		return l, nil // can't check
+	}
+	for _, k := range known {
+		if strings.ToLower(k.Code) == l {
+			return k.Code, nil
+		}
+	}
+	for _, k := range known {
+		if strings.ToLower(k.En) == l {
+			return k.Code, nil
+		}
+	}
+	best, bestDist := "", 3
+	if len(l) <= 3 {
+		bestDist = 2 // any two codes are only two letters apart
+	}
+	for _, k := range known {
+		for _, name := range []string{k.Code, strings.ToLower(k.En)} {
+			if d := levenshtein(l, name); d < bestDist {
+				best, bestDist = k.Code+" ("+k.En+")", d
+			}
+		}
+	}
+	if best != "" {
+		return "", fmt.Errorf("forvo doesn't know the language %q; did you mean %s?", l, best)
+	}
+	return "", fmt.Errorf("forvo doesn't know the language %q (see forvosay langs)", l)
+}
+
+// checkLangs validates (and canonicalizes) the -lang and -route languages.
+func checkLangs() error {
+	known, err := Languages()
+	if err != nil {
+		msg("warning: could not get forvo's language list to check -lang against:", err)
+		known = nil
+	}
+	canon := func(langs []string) ([]string, error) {
+		var out []string
+		for _, l := range langs {
+			c, err := canonLang(l, known)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, c)
+		}
+		return out, nil
+	}
+	langs, err := canon(langChain())
+	if err != nil {
+		return err
+	}
+	*lang = strings.Join(langs, ",")
+	for script, langs := range routes {
+		if routes[script], err = canon(langs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCanonLang(t *testing.T) {
+	known := []Language{{"de", "German"}, {"fr", "French"}, {"yue", "Cantonese"}, {"zh", "Mandarin Chinese"}}
+	for _, tc := range []struct {
+		l, want, err string
+	}{
+		{"de", "de", ""},
+		{" DE ", "de", ""},
+		{"german", "de", ""},
+		{"ger", "de", ""},
+		{"廣東話", "yue", ""},
+		{"mandarin chinese", "zh", ""},
+		{"frnch", "", "did you mean fr (French)"},
+		{"tlh", "", "see forvosay langs"},
+	} {
+		got, err := canonLang(tc.l, known)
+		if got != tc.want || (err == nil) != (tc.err == "") || err != nil && !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("canonLang(%q) = %q %v, want %q %q", tc.l, got, err, tc.want, tc.err)
+		}
+	}
+	if got, err := canonLang("German", nil); got != "de" || err != nil {
+		t.Errorf("without a language list, canonLang(German) = %q %v, want de", got, err)
+	}
+}
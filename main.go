@@ -6,7 +6,7 @@
 package main
 
 import (
-	"bufio"
+	"errors"
 	"flag"
 	"fmt"
 	"math/rand"
@@ -16,16 +16,14 @@ import (
 	"strings"
 	"sync"
 	"sync/atomic"
+	"text/template"
 	"time"
 	"unicode/utf8"
-
-	"github.com/atotto/clipboard"
-	"github.com/tevino/abool"
 )
 
 var word = flag.String("word", "", "lookup just this `word` and exit")
 
-var lang = flag.String("lang", "", "2 or 3 letter language `code`")
+var lang = flag.String("lang", "", "2 or 3 letter language `code` (or a name like german), or a comma-separated list of them to try in order (e.g. pt,gl,es)")
 var refreshCache = flag.Bool("refresh", false, "download results even if already in cache")
 
 var numSay = flag.Int("n", 1, "`max` number of pronunciations to play; < 0 for all")
@@ -33,7 +31,7 @@ var topSay = flag.Int("top", 5, "draw the N pronunciations to play randomly from
 
 var showFiles = flag.Bool("showFiles", false, "open the folder with the cached pronunciation files, instead of playing the files (using the command 'open')")
 var fallback = flag.String("fallback", "", "if no pronuncations are found, fallback to using the 'say' command with this `voice`")
-var nossl = flag.Bool("nossl", false, "don't use ssl when communicating with forvo.com; about twice as fast, but exposes your api key in plaintext")
+var nossl = flag.Bool("nossl", false, "don't use ssl when communicating with forvo.com; about twice as fast, but exposes your api key in plaintext (asks first, unless FORVOSAY_NOSSL=yes)")
 var bench = flag.Bool("bench", false, "time the request to forvo.com")
 
 var canto = flag.Bool("canto", false, "search cantonese.org for definitions")
@@ -96,7 +94,81 @@ func lookupDict(word string) {
 }
 
 func lookup(word string) error {
-	return lookupFancy(word, false, false, func(_ string) int { return 0 }, func(_ string) {}, func() bool { return true })
+	opts := lookupOpts{
+		options:       currentOptions(),
+		pick:          *pick,
+		getPlayCount:  func(_ string) int { return 0 },
+		incrPlayCount: func(_ string) {},
+		keepGoing:     func() bool { return true },
+	}
+	if opts.sentences && maybeSentence(word, opts.options) {
+		return readSentence(word, &heardWords{}, opts)
+	}
+	return lookupFancy(word, opts)
+}
+
+// options are the flags a lookup goes by, copied as it starts so that :set needn't wait for it.
+type options struct {
+	chain               []string // -lang
+	country             string
+	list, compare       bool
+	numSay, topSay      int
+	showFiles           bool
+	fallback            string
+	dict, canto, yi, bi bool
+	gi, yt              string
+	gt                  bool
+	define              bool
+	suggest, search     bool // -suggest, -suggestsearch
+	sentences           bool
+	gap                 time.Duration
+	inflect             bool
+	format              *template.Template
+}
+
+func currentOptions() options {
+	optionsMu.RLock()
+	defer optionsMu.RUnlock()
+	return options{
+		chain:     langChain(),
+		country:   *country,
+		list:      *listAll,
+		compare:   *compare,
+		numSay:    *numSay,
+		topSay:    *topSay,
+		showFiles: *showFiles,
+		fallback:  *fallback,
+		dict:      *dict,
+		canto:     *canto,
+		yi:        *yi,
+		bi:        *bi,
+		gi:        *gi,
+		yt:        *yt,
+		gt:        *gt,
+		define:    *defineFiles != "",
+		suggest:   *suggestFlag,
+		search:    *suggestSearch,
+		sentences: *sentences,
+		gap:       *gap,
+		inflect:   *inflect,
+		format:    formatTmpl,
+	}
+}
+
+type lookupOpts struct {
+	options
+	repeat        bool
+	onlyForvo     bool
+	inSentence    bool     // one of the words of a sentence (see -sentences)
+	pick          int      // if > 0, play just this pronunciation (numbered from 1, as by -list)
+	langs         []string // instead of -lang (e.g. per -route)
+	script        string   // what -route took the word to be written in
+	getPlayCount  func(string) int
+	incrPlayCount func(string)
+	keepGoing     func() bool
+	suggested     func([]string)                         // if non-nil, gets the suggestions for a word with no pronunciations, which are offered as s1, s2...
+	playing       func(item Pronunciation, listed []int) // if non-nil, told about each pronunciation as it starts playing, and the indexes of those listed (per -country)
+	found         func(req Req, items []Pronunciation)   // if non-nil, told the pronunciations (per -country) found in each language, before any play
 }
 
 func onlyMinimalPlayCounts(req Req, resp Resp, getPlayCount func(string) int) Resp {
@@ -120,108 +192,194 @@ func onlyMinimalPlayCounts(req Req, resp Resp, getPlayCount func(string) int) Re
 	return resp2
 }
 
-func lookupSentence(s string) error {
-	if *yt != "" {
-		lookupWebYandexTrans(*yt, s)
+func lookupSentence(s string, o options) error {
+	if o.yt != "" {
+		lookupWebYandexTrans(o.yt, s)
 	}
-	if *gt {
+	if o.gt {
 		lookupWebGoogleTrans(s)
 	}
 	return nil
 }
 
-// TODO: this api is jank af
-func lookupFancy(word string, repeat bool, onlyForvo bool, getPlayCount func(string) int, incrPlayCount func(string), keepGoing func() bool) error {
-	word = strings.TrimSpace(word)
-	word = strings.ToLower(word) // pretty sure forvo doesn't distinguish by case, so go ahead and normalize and get more use out of the cache
-	if !repeat && !onlyForvo {
-		if *dict {
+func lookupFancy(word string, opts lookupOpts) (err error) {
+	atomic.AddInt32(&lookupsRunning, 1)
+	defer atomic.AddInt32(&lookupsRunning, -1)
+	rep := newReport(word)
+	rep.Script = opts.script
+	defer func() { rep.finish(err) }()
+	langs := opts.langs
+	if langs == nil {
+		langs = opts.chain
+	}
+	if len(langs) == 0 {
+		return errors.New("no -lang (or -route) for this")
+	}
+	raw := word
+	word = normalizeWord(word, langs[0])
+	if word == "" {
+		return errors.New("nothing left to look up after stripping punctuation")
+	}
+	if !opts.repeat && !opts.onlyForvo && !opts.inSentence {
+		if opts.dict {
 			lookupDict(word)
 		}
-		if *canto {
+		if opts.canto {
 			lookupWebCanto(word)
 		}
-		if *yi {
+		if opts.yi {
 			lookupWebYandexImages(word)
 		}
-		if *gi != "" {
-			lookupWebGoogleImages(*gi, word)
+		if opts.gi != "" {
+			lookupWebGoogleImages(opts.gi, word)
 		}
-		if *bi {
+		if opts.bi {
 			lookupWebBaiduImages(word)
 		}
+		if opts.define {
+			rep.Definitions = printDefinitions(raw, langs, false)
+		}
 	}
-	req := Req{word, *lang}
-	resp, err := CacheResp(req)
-	if err != nil {
-		return fmt.Errorf("could not download results: %s", err)
+	var errs []error
+	found, numSaid := false, 0
+	for _, l := range langs {
+		f, n, err := lookupLang(raw, l, len(langs) > 1, opts, rep)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		found = found || f
+		numSaid += n
+		if n > 0 && !opts.compare || !opts.keepGoing() {
+			break // (something else has been looked up since; don't spend requests on this one)
+		}
 	}
-	if len(resp.Items) == 0 {
-		if *fallback != "" && !onlyForvo {
-			fmt.Println("no results; using 'say'")
-			if err := exec.Command("say", "-v", *fallback, word).Run(); err != nil {
+	if !found {
+		if opts.suggest && !opts.onlyForvo && !opts.repeat {
+			sugg := suggest(Req{word, langs[0]}, opts.search)
+			rep.Suggestions = sugg
+			printSuggestions(sugg, opts.suggested != nil)
+			if opts.suggested != nil {
+				opts.suggested(sugg)
+			}
+		}
+	}
+	if numSaid == 0 {
+		if opts.fallback != "" && !opts.onlyForvo {
+			msg("no results; using 'say'")
+			if err := exec.Command(sayCommand, "-v", opts.fallback, word).Run(); err != nil {
 				return fmt.Errorf("could not 'say': %v", err)
 			}
-		} else {
-			fmt.Println("no results")
+		} else if !found {
+			msg("no results")
+		}
+	}
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// lookupLang looks up (and plays) word in one language; found is whether there were any pronunciations,
+// and numSaid how many we played.
+func lookupLang(word, lang string, sayLang bool, opts lookupOpts, rep *lookupReport) (found bool, numSaid int, err error) {
+	word = normalizeWord(word, lang)
+	t0 := time.Now()
+	req, resp, err := resolve(word, lang, !opts.onlyForvo && opts.inflect)
+	if err != nil {
+		return false, 0, fmt.Errorf("could not download results: %s", err)
+	}
+	rep.addResp(req, *resp, time.Since(t0))
+	if len(resp.Items) == 0 {
+		return false, 0, nil
+	}
+	total := len(resp.Items)
+	*resp = onlyCountries(*resp, opts.country)
+	if len(resp.Items) == 0 {
+		msg("no pronunciations from", opts.country, "in", lang)
+		return false, 0, nil
+	}
+	if opts.found != nil {
+		opts.found(req, append([]Pronunciation{}, resp.Items...))
+	}
+	if sayLang {
+		msgf("%s (%s):\n", resp.Items[0].Langname, lang)
+	}
+	if pron := pronOf(req); pron != (pronInfo{}) {
+		msgf("%s [%v]\n", req.Word, pron)
+	}
+	var listed []int
+	for _, item := range resp.Items {
+		listed = append(listed, item.Index)
+	}
+	if opts.list && !opts.repeat {
+		listItems(req, *resp, total, opts.format)
+	}
+	if opts.pick > 0 {
+		*resp = onlyPick(*resp, opts.pick)
+		if len(resp.Items) == 0 && len(listed) < total {
+			return true, 0, fmt.Errorf("no pronunciation #%d from %s", opts.pick, opts.country)
+		} else if len(resp.Items) == 0 {
+			return true, 0, fmt.Errorf("no pronunciation #%d (there are %d)", opts.pick, total)
 		}
 	} else {
-		origN := len(resp.Items)
-		*resp = onlyMinimalPlayCounts(req, *resp, getPlayCount)
-		n := len(resp.Items)
-		numSay := *numSay
-		topSay := *topSay
-		if numSay < 0 || numSay > n {
-			numSay = n
-		}
-		if topSay < 0 || topSay > n {
-			topSay = n
-		}
-		rand.Shuffle(topSay, func(i, j int) {
-			resp.Items[i], resp.Items[j] = resp.Items[j], resp.Items[i]
-		})
-
-		if *showFiles {
-			if err := exec.Command("open", req.CacheDir()).Run(); err != nil {
-				fatal("could not show files:", err)
-			}
-			numSay = 0
+		*resp = onlyMinimalPlayCounts(req, *resp, opts.getPlayCount)
+	}
+	n := len(resp.Items)
+	numSay := opts.numSay
+	topSay := opts.topSay
+	if numSay < 0 || numSay > n {
+		numSay = n
+	}
+	if topSay < 0 || topSay > n {
+		topSay = n
+	}
+	rand.Shuffle(topSay, func(i, j int) {
+		resp.Items[i], resp.Items[j] = resp.Items[j], resp.Items[i]
+	})
+
+	if opts.showFiles {
+		if err := exec.Command("open", req.CacheDir()).Run(); err != nil {
+			fatal("could not show files:", err)
 		}
+		return true, 1, nil // as good as played
+	}
 
-		var errs []error
-		numSaid := 0
-		CacheMP3s(req, *resp, func(mp3 MaybeMP3) {
-			if mp3.Err != nil {
-				errs = append(errs, fmt.Errorf("could not download mp3: %v", mp3.Err))
-				return
-			}
-			if numSaid < numSay && keepGoing() {
-				numSaid++
-				fmt.Println(mp3.Fname, "of", origN)
-				incrPlayCount(mp3.Fname)
-				err := PlayMP3(mp3.Fname)
-				if err != nil {
-					errs = append(errs, fmt.Errorf("could not play mp3: %v (will delete file)", err))
-					os.Remove(mp3.Fname)
-					numSaid--
-				}
+	var errs []error
+	stopped := false
+	CacheMP3s(req, *resp, func(mp3 MaybeMP3) {
+		if mp3.Err != nil {
+			errs = append(errs, fmt.Errorf("could not download mp3: %v", mp3.Err))
+			rep.mp3Err(errs[len(errs)-1])
+			return
+		}
+		if numSaid < numSay && opts.keepGoing() && !stopped {
+			numSaid++
+			msg(formatItem(req, mp3.Item, total, opts.format))
+			opts.incrPlayCount(mp3.Fname)
+			if opts.playing != nil {
+				opts.playing(mp3.Item, listed)
 			}
-		})
-		if numSaid == 0 && *fallback != "" && !onlyForvo {
-			fmt.Println("no results; using 'say'")
-			if err := exec.Command("say", "-v", *fallback, word).Run(); err != nil {
-				return fmt.Errorf("could not 'say': %v", err)
+			err := PlayMP3(mp3.Fname)
+			if err == errStopped {
+				stopped = true // and don't play any more of them
+			} else if err != nil {
+				errs = append(errs, fmt.Errorf("could not play mp3: %v (will delete file)", err))
+				rep.mp3Err(errs[len(errs)-1])
+				os.Remove(mp3.Fname)
+				numSaid--
+			} else {
+				rep.played(mp3.Item)
 			}
 		}
-		if len(errs) > 0 {
-			return errs[0]
-		}
+	})
+	if len(errs) > 0 {
+		return true, numSaid, errs[0]
 	}
-	return nil
+	return true, numSaid, nil
 }
 
-func maybePassword(s string) bool {
-	if maybeSentence(s) {
+func maybePassword(s string, o options) bool {
+	if maybeSentence(s, o) {
 		return false // if it's (probably) a sentence, it's (probably) not a password
 	}
 	n := 0
@@ -233,21 +391,24 @@ func maybePassword(s string) bool {
 	return n >= 2
 }
 
-func maybeSentence(s string) bool {
-	if *yt == "" && !*gt {
+func maybeSentence(s string, o options) bool {
+	if o.yt == "" && !o.gt && !o.sentences {
 		return false // no translate set so always assume not sentence
 	}
-	if *canto {
+	if o.sentences && hasCJK(s) && haveSegDict() {
+		return !inSegDict(s) && len(segment(s)) > 1
+	}
+	if o.canto {
 		return utf8.RuneCountInString(s) >= 5
 	}
 	return len(strings.Fields(s)) >= 4
 }
 
-func shouldSkip(s string) bool {
+func shouldSkip(s string, o options) bool {
 	if utf8.RuneCountInString(s) > 1000 {
 		return true
 	}
-	if maybePassword(s) {
+	if maybePassword(s, o) {
 		return true
 	}
 	return false
@@ -269,97 +430,177 @@ func trackPlayCounts() (get func(string) int, incr func(string)) {
 	return
 }
 
-// lookup words from clipboard forever
+// lookup words from clipboard (or with -stdin, from stdin) forever
 func lookupForever() {
-	var prev string
 	var w int32
-	var word atomic.Value
-	repeat := abool.New()
+	c := newControl()
 	getPlayCount, incrPlayCount := trackPlayCounts()
+	var heard heardWords
+	if *prefetchShare > 0 {
+		go prefetchForever()
+	}
+	c.start = func(s string, r bool, p int) <-chan struct{} {
+		c.word.Store(s)
+		if !r {
+			c.looked(s)
+		}
+		if atomic.LoadInt32(&w) > 0 && !r {
+			msg()
+		}
+		langs, script, routed := routeLangs(s)
+		if routed && !r {
+			msgf("%s: %s\n", script, strings.Join(langs, ","))
+		}
+		this := atomic.AddInt32(&w, 1)
+		done := make(chan struct{})
+		opts := lookupOpts{
+			options:       currentOptions(),
+			repeat:        r,
+			pick:          p,
+			langs:         langs,
+			script:        script,
+			getPlayCount:  getPlayCount,
+			incrPlayCount: incrPlayCount,
+			keepGoing:     func() bool { return atomic.AddInt32(&w, 0) == this },
+			suggested:     func(sugg []string) { c.suggestions.Store(sugg) },
+			playing:       c.playing,
+		}
+		go func() {
+			defer close(done)
+			if err := lookupCopied(s, &heard, opts); err != nil {
+				msgf("error looking up `%v`: %v\n", s, err)
+			}
+		}()
+		return done
+	}
+	if *fromStdin {
+		readStdinWords(c, os.Stdin)
+		return
+	}
 	go func() {
-		b := bufio.NewReader(os.Stdin)
+		lines := newLineReader()
+		if *keys && !lines.tty {
+			msg("-keys needs stdin to be a terminal; reading lines instead")
+		}
 		for {
-			s, err := b.ReadString('\n')
+			optionsMu.RLock()
+			k := *keys
+			optionsMu.RUnlock()
+			var err error
+			if k && lines.tty {
+				err = c.handleKey(lines)
+			} else {
+				var s string
+				if s, err = lines.readLine(""); err == nil {
+					c.handleInput(s)
+				}
+			}
 			if err != nil {
-				fmt.Println("error reading input:", err, "giving up...")
+				restoreTerminal()
+				msg("error reading input:", err, "giving up...")
 				return
 			}
-			s = strings.TrimSpace(s)
-			if s == "" {
-				repeat.Set()
-				continue
-			}
-			w, _ := word.Load().(string)
-			if w == "" {
-				continue
-			}
-			switch s {
-			case "d":
-				lookupDict(w)
-			case "y":
-				lookupWebYandexImages(w)
-			case "g":
-				lookupWebGoogleImages(*lang, w)
-			case "c":
-				lookupWebCanto(w)
-			default:
-				fmt.Println("unknown input:", s)
-				fmt.Println("try: d, y, g, c")
-			}
 		}
 	}()
+	pollClipboard(c)
+}
+
+// pollClipboard looks up whatever's copied to the clipboard (and what's asked for on stdin), forever.
+func pollClipboard(c *control) {
+	var prev string
+	cw := newClipWatcher()
 	for i := 0; ; i++ {
 		if i > 0 {
-			time.Sleep(10 * time.Millisecond)
+			cw.wait(c.wakeup)
 		}
-		s, err := clipboard.ReadAll()
+		s, err := readClipboard()
 		s = strings.TrimSpace(s)
-		r := repeat.IsSet()
+		r := c.repeat.IsSet()
 		if err != nil || (s == prev && !r) || s == "" {
+			cw.changed(false)
 			continue
 		}
-		repeat.UnSet()
-		word.Store(s)
-		prev = s
+		cw.changed(true)
+		c.repeat.UnSet()
+		p := int(atomic.SwapInt32(&c.pickNum, 0))
+		optionsMu.RLock()
+		if p == 0 {
+			p = *pick
+		}
+		optionsMu.RUnlock()
+		if r && s == prev {
+			s = c.lastWord() // repeat whatever we looked up last, even if it didn't come from the clipboard
+		} else {
+			prev = s
+		}
 		if i == 0 {
 			// skip whatever's initially on the clipboard (somehow it's annoying to pick this up)
+			c.word.Store(s)
 			continue
 		}
-		if shouldSkip(s) {
-			fmt.Println("skipping word that looks like a password or very long body of text")
+		if shouldSkip(s, currentOptions()) {
+			msg("skipping word that looks like a password or very long body of text")
 			continue
 		}
-		if i > 1 && !r {
-			fmt.Println()
-		}
-		onlyForvo := false
-		if maybeSentence(s) {
-			fmt.Printf("looking up sentence `%v`...\n", s)
-			lookupSentence(s)
-			onlyForvo = true
-		}
-		this := atomic.AddInt32(&w, 1)
-		go func() {
-			err = lookupFancy(s, r, onlyForvo, getPlayCount, incrPlayCount, func() bool { return atomic.AddInt32(&w, 0) == this })
-			if err != nil {
-				fmt.Printf("error looking up `%v`: %v\n", s, err)
-			}
-		}()
+		c.start(s, r, p)
+	}
+}
+
+// commands are run as `forvosay [<options>] <command> [<command options>]`
+var commands = map[string]command{
+	"fakeserver": {runFakeServer, false},
+	"import":     {runImport, false},
+	"search":     {runSearch, true},
+	"popular":    {runPopular, true},
+	"langs":      {runLangs, true},
+	"standard":   {runStandard, true},
+}
+
+type command struct {
+	run    func(args []string)
+	online bool // needs api keys
+}
+
+func needLang() {
+	if len(langChain()) == 0 {
+		fatal("must pass -lang")
 	}
 }
 
 func main() {
 	flag.Usage = func() {
 		fmt.Fprint(os.Stderr, os.Args[0], ` -lang <lang> [<options>]
+       `, os.Args[0], ` -lang <lang> -stdin [<options>]
+       `, os.Args[0], ` -lang <lang> -batch [<options>] [<wordlist>...]
+       `, os.Args[0], ` [<options>] <command> [<command options>]
 
 Pronounce words copied to the clipboard; pronunciations are downloaded from
-forvo.com.
+forvo.com. Type :help (and enter) for what else you can do while it runs.
+With -stdin, pronounce each line of stdin instead, one after another.
+
+With -batch, instead download (without playing) pronunciations for every word
+in the given word lists, or stdin. With -enqueue, add them to a queue that is
+worked through a little at a time, spending at most the -prefetch fraction of
+each day's api quota.
 
 Results are cached in ~/.forvocache.
 
+commands:
+
+  search      list words starting with a prefix (-all: even unpronounced ones)
+  popular     list the most popular pronounced words
+  standard    play forvo's standard pronunciation of a word
+  langs       list the languages forvo knows (-min N: with at least N pronunciations),
+              with how many words of each are cached here (forvo's language
+              list doesn't say how many pronunciations each has)
+  fakeserver  serve the forvo api from the cache, for use offline
+  import      index ipa/romanizations from kaikki.org jsonl or CC-CEDICT/CC-Canto files
+              (and the definitions in jsonl files, for -define)
+
 dependencies:
 
-- FORVO_API_KEY must be set in your environment
+- FORVO_API_KEY must be set in your environment, or keys put in ~/.forvokey
+  (several keys are used in turn as each runs out for the day)
 - afplay must be in your PATH (in /usr/bin on macs)
 
 options:
@@ -370,16 +611,79 @@ options:
 	if *yt != "" && (*yt)[0] == '-' {
 		fatal("bad yt argument: ", *yt, " -- expected <LANG>-<LANG>")
 	}
-	*chrome = "," + *chrome + ","
-	if len(flag.Args()) > 0 {
-		fatal("unknown argument:", flag.Args()[0])
+	if *col < 1 {
+		fatal("bad col argument:", *col, "-- columns are numbered from 1")
 	}
+	if *prefetchOrder != "list" && *prefetchOrder != "freq" {
+		fatal("bad prefetchorder argument:", *prefetchOrder, "-- expected list or freq")
+	}
+	*chrome = "," + *chrome + ","
 	rand.Seed(int64(time.Now().Unix()))
-	if *lang == "" {
-		fatal("must pass -lang")
+	var cmd *command
+	if len(flag.Args()) > 0 && !*batch && !*enqueue {
+		c, ok := commands[flag.Arg(0)]
+		if !ok {
+			fatal("unknown argument:", flag.Arg(0))
+		}
+		if !c.online {
+			c.run(flag.Args()[1:])
+			return
+		}
+		cmd = &c
+	} else if *route == "" || *word != "" || *batch || *enqueue {
+		needLang()
+	}
+	if err := parseRoutes(); err != nil {
+		fatal(err)
+	}
+	if err := checkSelection(); err != nil {
+		fatal(err)
+	}
+	if *jsonOut {
+		msgOut = os.Stderr
+	}
+	if err := parseFormat(); err != nil {
+		fatal(err)
+	}
+	if err := loadKeys(); err != nil {
+		fatal("could not load api keys:", err)
+	}
+	if len(apiKeys) == 0 {
+		fatal("must set FORVO_API_KEY in environment (or put keys in", *keyFile+")")
+	}
+	useTransport()
+	if *nossl {
+		if err := confirmNoSSL(); err != nil {
+			fatal(err)
+		}
 	}
-	if apiKey == "" {
-		fatal("must set FORVO_API_KEY in environment")
+	if cmd == nil || flag.Arg(0) != "langs" {
+		if err := checkLangs(); err != nil {
+			fatal(err)
+		}
+	}
+	if cmd != nil {
+		cmd.run(flag.Args()[1:])
+		return
+	}
+	if *diag {
+		runDiag(Req{normalizeWord(*word, firstLang()), firstLang()})
+		return
+	}
+	if *batch {
+		runBatch(flag.Args())
+		return
+	}
+	if *enqueue {
+		n, err := enqueueWords(readWordFiles(flag.Args()), strings.Join(langChain(), ","))
+		if err != nil {
+			fatal("could not add to prefetch queue:", err)
+		}
+		msg("queued", n, "new words for prefetching")
+		if *prefetchShare > 0 {
+			drainQueue()
+		}
+		return
 	}
 	if *word != "" {
 		err := lookup(*word)
@@ -389,10 +693,18 @@ options:
 		return
 	}
 
+	if *tui {
+		if *fromStdin {
+			fatal("-tui looks up words from the clipboard, not -stdin")
+		}
+		runTUI()
+		return
+	}
 	lookupForever()
 }
 
 func fatal(v ...interface{}) {
-	fmt.Fprintln(os.Stderr, v...)
+	restoreTerminal()
+	fmt.Fprint(os.Stderr, redact(fmt.Sprintln(v...)))
 	os.Exit(1)
 }
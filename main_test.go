@@ -1,10 +1,70 @@
 package main
 
 import (
-	"fmt"
+	"io/ioutil"
+	"os"
 	"testing"
 )
 
+// useFixtures answers http requests from testdata (see -record/-replay) and caches into a temp dir,
+// returning a func to put things back.
+func useFixtures(t *testing.T) func() {
+	oldReplay, oldKeys, oldCacheDir := *replay, apiKeys, cacheDir
+	dir, err := ioutil.TempDir("", "forvosay-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	*replay = "testdata"
+	useTransport()
+	apiKeys = []string{"testkey"}
+	cacheDir = dir
+	return func() {
+		*replay, apiKeys, cacheDir = oldReplay, oldKeys, oldCacheDir
+		useTransport()
+		os.RemoveAll(dir)
+	}
+}
+
 func TestBasic(t *testing.T) {
-	fmt.Println(Get(Req{"ich", "de"}))
+	defer useFixtures(t)()
+	resp, err := Get(Req{"ich", "de"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Items) != 2 || resp.Items[0].Word != "ich" || resp.Items[1].Index != 1 {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+	if u := loadUsage(); u.Requests != 0 {
+		t.Errorf("replayed response counted against the quota: %+v", u)
+	}
+}
+
+func TestCacheMP3s(t *testing.T) {
+	defer useFixtures(t)()
+	req := Req{"ich", "de"}
+	resp, err := CacheResp(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	CacheMP3s(req, *resp, func(mp3 MaybeMP3) {
+		if mp3.Err != nil {
+			t.Error(mp3.Err)
+			return
+		}
+		if _, err := os.Stat(mp3.Fname); err != nil {
+			t.Error(err)
+		}
+		n++
+	})
+	if n != 2 {
+		t.Errorf("got %d mp3s, want 2", n)
+	}
+}
+
+func TestReplayMiss(t *testing.T) {
+	defer useFixtures(t)()
+	if _, err := Get(Req{"nicht aufgenommen", "de"}); err == nil {
+		t.Error("expected an error for a request that wasn't recorded")
+	}
 }
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// normalizeWord turns text copied from wherever into the form we look up (and cache) for lang:
+// stray punctuation and invisible characters are stripped, accents are composed (NFC) and case is folded.
+func normalizeWord(word, lang string) string {
+	word = cleanWord(word)
+	if fold, ok := caseFolders[baseLang(lang)]; ok {
+		return fold(word)
+	}
+	return strings.ToLower(word) // pretty sure forvo doesn't distinguish by case, so go ahead and normalize and get more use out of the cache
+}
+
+// cleanWord is normalizeWord without the case folding, for looking things up where case matters.
+func cleanWord(word string) string {
+	word = stripInvisible(word)
+	word = composeMarks(word)
+	word = strings.Replace(word, "’", "'", -1) // forvo has l'homme, not l’homme
+	for {
+		w := strings.TrimSpace(trimPunct(stripFootnote(strings.TrimSpace(word))))
+		if w == word {
+			break
+		}
+		word = w
+	}
+	return strings.Join(strings.Fields(word), " ")
+}
+
+// baseLang is the language part of a forvo code: "zh" for "zh_TW".
+func baseLang(lang string) string {
+	if i := strings.IndexAny(lang, "_-"); i >= 0 {
+		return lang[:i]
+	}
+	return lang
+}
+
+// caseFolders are for languages where strings.ToLower gets it wrong.
+var caseFolders = map[string]func(string) string{
+	"tr": turkishLower,
+	"az": turkishLower,
+	"el": greekLower,
+}
+
+// turkishLower knows that I is the capital of ı, and İ of i.
+func turkishLower(s string) string {
+	return strings.ToLowerSpecial(unicode.TurkishCase, s)
+}
+
+// greekLower knows that a lowercase sigma at the end of a word is ς.
+func greekLower(s string) string {
+	rs := []rune(strings.ToLower(s))
+	for i, r := range rs {
+		if r == 'σ' && i > 0 && unicode.IsLetter(rs[i-1]) && (i+1 == len(rs) || !unicode.IsLetter(rs[i+1])) {
+			rs[i] = 'ς'
+		}
+	}
+	return string(rs)
+}
+
+// stripInvisible removes soft hyphens (which come along with words copied from justified text) and zero-width spaces.
+func stripInvisible(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '\u00ad', '\u200b', '\u2060', '\ufeff': // soft hyphen, zero width space, word joiner, bom
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// trimPunct strips a quote, bracket or bit of sentence punctuation from each end of a word (but not an
+// apostrophe or hyphen that belongs to it, as in l'homme or -chen); one at a time, so footnotes are seen.
+func trimPunct(s string) string {
+	isPunct := func(r rune) bool {
+		return r != '\'' && r != '-' && (unicode.IsPunct(r) || unicode.IsSymbol(r))
+	}
+	if r, n := utf8.DecodeRuneInString(s); n > 0 && isPunct(r) {
+		s = s[n:]
+	}
+	if r, n := utf8.DecodeLastRuneInString(s); n > 0 && isPunct(r) {
+		s = s[:len(s)-n]
+	}
+	return s
+}
+
+// stripFootnote removes a trailing footnote marker: word[12], word¹, word*, word†.
+func stripFootnote(s string) string {
+	if i := strings.LastIndex(s, "["); i > 0 && strings.HasSuffix(s, "]") && isDigits(s[i+1:len(s)-1]) {
+		return s[:i]
+	}
+	return strings.TrimRightFunc(s, func(r rune) bool {
+		return strings.ContainsRune("⁰¹²³⁴⁵⁶⁷⁸⁹*†‡§", r)
+	})
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// compositions is the inverse of decompositions.
+var compositions = func() map[[2]rune]rune {
+	m := map[[2]rune]rune{}
+	for r, d := range decompositions {
+		m[d] = r
+	}
+	return m
+}()
+
+// composeMarks combines letters followed by combining marks into precomposed letters, where there is one;
+// this is NFC as far as the letters in decompositions go, so "café" and "café" are the same word.
+func composeMarks(s string) string {
+	rs := []rune(s)
+	out := rs[:0]
+	starter := -1 // index in out of the letter marks would combine with
+	for _, r := range rs {
+		if unicode.Is(unicode.Mn, r) && starter >= 0 {
+			if c, ok := compositions[[2]rune{out[starter], r}]; ok {
+				out[starter] = c
+				continue
+			}
+		}
+		if !unicode.Is(unicode.Mn, r) {
+			starter = len(out)
+		}
+		out = append(out, r)
+	}
+	return string(out)
+}
@@ -0,0 +1,41 @@
+package main
+
+import "testing"
+
+func TestNormalizeWord(t *testing.T) {
+	for _, tc := range []struct{ in, lang, want string }{
+		{"  Hund ", "de", "hund"},
+		{"ISTANBUL", "tr", "ıstanbul"},
+		{"İstanbul", "tr", "istanbul"},
+		{"ΟΔΟΣ", "el", "οδος"},
+		{"café", "fr", "café"},
+		{"Việt", "vi", "việt"},
+		{"«Bonjour!»", "fr", "bonjour"},
+		{"“l’homme,”", "fr", "l'homme"},
+		{"¿Qué?", "es", "qué"},
+		{"Verantwor­tung", "de", "verantwortung"},
+		{"Wort[12]", "de", "wort"},
+		{"(Wort[3]).", "de", "wort"},
+		{"Wort¹", "de", "wort"},
+		{"「日本」", "ja", "日本"},
+		{"-chen", "de", "-chen"},
+		{"good  morning", "en", "good morning"},
+	} {
+		if got := normalizeWord(tc.in, tc.lang); got != tc.want {
+			t.Errorf("normalizeWord(%q, %q) = %q, want %q", tc.in, tc.lang, got, tc.want)
+		}
+	}
+}
+
+func TestFoldDiacritics(t *testing.T) {
+	for in, want := range map[string]string{
+		"café":     "cafe",
+		"café":    "cafe",
+		"Ångström": "Angstrom",
+		"việt":     "viet",
+	} {
+		if got := foldDiacritics(in); got != want {
+			t.Errorf("foldDiacritics(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+var jsonOut = flag.Bool("json", false, "print one json object per lookup on stdout (human-readable messages go to stderr instead)")
+
+// msgOut is where human-readable messages go; with -json that's stderr, so stdout has nothing but json.
+var msgOut io.Writer = os.Stdout
+
+func msg(v ...interface{}) {
+	fmt.Fprint(msgOut, redact(fmt.Sprintln(v...)))
+}
+
+func msgf(format string, v ...interface{}) {
+	fmt.Fprint(msgOut, redact(fmt.Sprintf(format, v...)))
+}
+
+// lookupReport is the json emitted for each lookup with -json.
+type lookupReport struct {
+	Event       string // always "lookup"
+	Time        time.Time
+	Word        string // the word as given, before normalization
+	Script      string `json:",omitempty"` // if -route picked the language
+	Req         Req    // the first one with pronunciations (or else the first tried)
+	Tried       []Req  // every language tried, with -lang a,b,c
+	CacheDir    string
+	RespFname   string
+	Items       []reportItem
+	IPA         string   `json:",omitempty"` // from the files indexed by `forvosay import`
+	Roman       string   `json:",omitempty"`
+	Definitions []string `json:",omitempty"` // from the -define dictionaries
+	Suggestions []string `json:",omitempty"` // when there are no Items
+	MP3Errors   []string `json:",omitempty"`
+	Error       string   `json:",omitempty"`
+	Timings     struct {
+		RespMS  float64 // fetching the pronunciation list (from cache or forvo)
+		MP3MS   float64 // fetching and playing the mp3s
+		TotalMS float64
+	}
+
+	t0, tMP3 time.Time
+	mu       sync.Mutex
+}
+
+type reportItem struct {
+	Pronunciation
+	Num    int
+	Fname  string
+	Played bool
+}
+
+func newReport(word string) *lookupReport {
+	t0 := time.Now()
+	return &lookupReport{Event: "lookup", Time: t0, Word: word, t0: t0}
+}
+
+// addResp records the pronunciations found for req (one of the languages tried, with -lang a,b,c).
+func (rep *lookupReport) addResp(req Req, resp Resp, respTime time.Duration) {
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	rep.Tried = append(rep.Tried, req)
+	rep.Timings.RespMS += ms(respTime)
+	if len(resp.Items) > 0 && rep.Req.Word == "" {
+		rep.setReq(req)
+	}
+	for _, item := range resp.Items {
+		rep.Items = append(rep.Items, reportItem{item, item.Index + 1, req.CacheMP3Fname(item.Index), false})
+	}
+	if rep.tMP3.IsZero() {
+		rep.tMP3 = time.Now()
+	}
+}
+
+func (rep *lookupReport) setReq(req Req) {
+	rep.Req = req
+	rep.CacheDir = req.CacheDir()
+	rep.RespFname = req.CacheFname()
+	pron := pronOf(req)
+	rep.IPA, rep.Roman = pron.IPA, pron.Roman
+}
+
+func (rep *lookupReport) played(item Pronunciation) {
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	for i := range rep.Items {
+		if rep.Items[i].Id == item.Id {
+			rep.Items[i].Played = true
+		}
+	}
+}
+
+func (rep *lookupReport) mp3Err(err error) {
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	rep.MP3Errors = append(rep.MP3Errors, err.Error())
+}
+
+var jsonMu sync.Mutex
+
+// finish emits the report, if -json was given.
+func (rep *lookupReport) finish(err error) {
+	if !*jsonOut {
+		return
+	}
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	if err != nil {
+		rep.Error = err.Error()
+	}
+	if rep.Req.Word == "" && len(rep.Tried) > 0 {
+		rep.setReq(rep.Tried[0])
+	}
+	if !rep.tMP3.IsZero() {
+		rep.Timings.MP3MS = ms(time.Since(rep.tMP3))
+	}
+	rep.Timings.TotalMS = ms(time.Since(rep.t0))
+	jsonMu.Lock()
+	defer jsonMu.Unlock()
+	writeJSON(rep)
+}
+
+// writeJSON writes v as one line of json on stdout; callers hold jsonMu.
+func writeJSON(v interface{}) {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "could not write json:", err)
+		return
+	}
+	fmt.Println(redact(string(buf)))
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"io"
+	"strings"
+)
+
+var fromStdin = flag.Bool("stdin", false, "look up each line of stdin, instead of what's copied to the clipboard")
+var syncPlay = flag.Bool("sync", true, "with -stdin, finish playing each word before reading the next; with -sync=false, each line cuts off the one before, like copying a word does")
+
+// readStdinWords looks up each line of stdin, for -stdin, skipping lines seen already and the same things
+// as the clipboard loop does.
+func readStdinWords(c *control, in io.Reader) {
+	seen := map[string]bool{}
+	var done <-chan struct{}
+	b := bufio.NewScanner(in)
+	b.Buffer(nil, 1<<20)
+	for b.Scan() {
+		s := strings.TrimSpace(b.Text())
+		if s == "" || seen[s] {
+			continue
+		}
+		seen[s] = true
+		if shouldSkip(s, currentOptions()) {
+			msg("skipping word that looks like a password or very long body of text")
+			continue
+		}
+		done = c.start(s, false, *pick)
+		if *syncPlay {
+			<-done
+		}
+	}
+	if err := b.Err(); err != nil {
+		msg("error reading input:", err)
+	}
+	if done != nil {
+		<-done // let the last word finish
+	}
+}
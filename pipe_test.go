@@ -0,0 +1,30 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadStdinWords(t *testing.T) {
+	var looked []string
+	running := false
+	c := newControl()
+	c.start = func(s string, r bool, p int) <-chan struct{} {
+		if running {
+			t.Errorf("started %q before the word before it was done", s)
+		}
+		running = true
+		looked = append(looked, s)
+		done := make(chan struct{})
+		go func() {
+			running = false
+			close(done)
+		}()
+		return done
+	}
+	readStdinWords(c, strings.NewReader("Hund\nKatze\n\nHund\n  Katze \nMaus\n"))
+	if want := []string{"Hund", "Katze", "Maus"}; !reflect.DeepEqual(looked, want) {
+		t.Errorf("looked up %q, want %q", looked, want)
+	}
+}
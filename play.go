@@ -1,9 +1,53 @@
 package main
 
 import (
+	"errors"
 	"os/exec"
+	"sync"
 )
 
+// sayCommand speaks a word for -fallback, instead of playing a pronunciation (replaced in tests).
+var sayCommand = "say"
+
+// errStopped is returned by PlayMP3 when stopPlayback cut it short.
+var errStopped = errors.New("playback stopped")
+
+// the players running now, so they can be stopped
+var players struct {
+	sync.Mutex
+	cmds    map[*exec.Cmd]bool
+	stopped map[*exec.Cmd]bool
+}
+
 func PlayMP3(fname string) error {
-	return exec.Command("afplay", fname).Run()
+	cmd := exec.Command("afplay", fname)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	players.Lock()
+	if players.cmds == nil {
+		players.cmds = map[*exec.Cmd]bool{}
+		players.stopped = map[*exec.Cmd]bool{}
+	}
+	players.cmds[cmd] = true
+	players.Unlock()
+	err := cmd.Wait()
+	players.Lock()
+	defer players.Unlock()
+	delete(players.cmds, cmd)
+	if players.stopped[cmd] {
+		delete(players.stopped, cmd)
+		return errStopped
+	}
+	return err
+}
+
+// stopPlayback kills whatever is playing.
+func stopPlayback() {
+	players.Lock()
+	defer players.Unlock()
+	for cmd := range players.cmds {
+		players.stopped[cmd] = true
+		cmd.Process.Kill()
+	}
 }
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var enqueue = flag.Bool("enqueue", false, "add the words in the files given as arguments (or stdin) to the prefetch queue, instead of fetching them now; see -prefetch")
+var prefetchShare = flag.Float64("prefetch", 0, "spend at most this `fraction` of the daily -quota working through the prefetch queue, in the background (or, with -enqueue, right away)")
+var prefetchOrder = flag.String("prefetchorder", "list", "prefetch queued words in `order` list (as queued) or freq (most often queued first)")
+
+// queueItem is a word waiting to be prefetched.
+type queueItem struct {
+	Word  string
+	Lang  string
+	Seq   int // position in the order words were queued
+	Freq  int // number of times the word was queued
+	Fails int
+}
+
+// prefetchQueue is kept in the cache dir, so it survives across runs (and days).
+type prefetchQueue struct {
+	NextSeq int
+	Items   []queueItem
+}
+
+const maxPrefetchFails = 3
+
+var queueMu sync.Mutex
+
+// lookupsRunning counts lookups for the user (e.g. from the clipboard), which take priority over prefetching.
+var lookupsRunning int32
+
+func queueFname() string {
+	return cacheDir + "/.prefetch.json"
+}
+
+func loadQueue() (prefetchQueue, error) {
+	var q prefetchQueue
+	buf, err := ioutil.ReadFile(queueFname())
+	if os.IsNotExist(err) {
+		return q, nil
+	}
+	if err != nil {
+		return q, err
+	}
+	err = json.Unmarshal(buf, &q)
+	return q, err
+}
+
+func saveQueue(q prefetchQueue) error {
+	buf, err := json.MarshalIndent(&q, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(cacheDir, 0777); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(queueFname(), buf, 0666)
+}
+
+// enqueueWords adds words to the prefetch queue; a word that's already queued just has its frequency bumped.
+func enqueueWords(words []string, lang string) (int, error) {
+	queueMu.Lock()
+	defer queueMu.Unlock()
+	q, err := loadQueue()
+	if err != nil {
+		return 0, err
+	}
+	idx := map[Req]int{}
+	for i, item := range q.Items {
+		idx[Req{item.Word, item.Lang}] = i
+	}
+	added := 0
+	for _, w := range words {
+		req := Req{normalizeWord(w, strings.Split(lang, ",")[0]), lang}
+		if i, ok := idx[req]; ok {
+			q.Items[i].Freq++
+			continue
+		}
+		idx[req] = len(q.Items)
+		q.Items = append(q.Items, queueItem{Word: req.Word, Lang: lang, Seq: q.NextSeq, Freq: 1})
+		q.NextSeq++
+		added++
+	}
+	return added, saveQueue(q)
+}
+
+// sortQueue puts items in the order to prefetch them, per order (see -prefetchorder).
+func sortQueue(items []queueItem, order string) {
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].Fails != items[j].Fails {
+			return items[i].Fails < items[j].Fails // give the others a chance before retrying
+		}
+		if order == "freq" && items[i].Freq != items[j].Freq {
+			return items[i].Freq > items[j].Freq
+		}
+		return items[i].Seq < items[j].Seq
+	})
+}
+
+func nextQueued(order string) (queueItem, bool, error) {
+	queueMu.Lock()
+	defer queueMu.Unlock()
+	q, err := loadQueue()
+	if err != nil || len(q.Items) == 0 {
+		return queueItem{}, false, err
+	}
+	sortQueue(q.Items, order)
+	return q.Items[0], true, nil
+}
+
+// dequeue drops item from the queue if it's done, or else counts a failure (dropping it after too many).
+func dequeue(item queueItem, done bool) error {
+	queueMu.Lock()
+	defer queueMu.Unlock()
+	q, err := loadQueue()
+	if err != nil {
+		return err
+	}
+	for i := range q.Items {
+		if q.Items[i].Word != item.Word || q.Items[i].Lang != item.Lang {
+			continue
+		}
+		q.Items[i].Fails++
+		if done || q.Items[i].Fails >= maxPrefetchFails {
+			q.Items = append(q.Items[:i], q.Items[i+1:]...)
+		}
+		break
+	}
+	return saveQueue(q)
+}
+
+// prefetchAllowed reports whether prefetching may spend another request today, given the -prefetch share.
+func prefetchAllowed(share float64) bool {
+	if *quota <= 0 {
+		return true
+	}
+	usageMu.Lock()
+	defer usageMu.Unlock()
+	u := loadUsage()
+	return float64(u.Prefetched) < share*float64(totalQuota()) && u.Requests < totalQuota()
+}
+
+func notePrefetch() {
+	usageMu.Lock()
+	defer usageMu.Unlock()
+	u := loadUsage()
+	u.Prefetched++
+	if err := saveUsage(u); err != nil {
+		msg("warning: could not save api usage:", err)
+	}
+}
+
+var errShareUsed = errors.New("prefetch share of today's quota is used up")
+
+// prefetchOne prefetches the next queued word; it returns false if the queue is empty.
+func prefetchOne() (bool, error) {
+	optionsMu.RLock()
+	order, share, refresh := *prefetchOrder, *prefetchShare, *refreshCache
+	optionsMu.RUnlock()
+	item, ok, err := nextQueued(order)
+	if !ok || err != nil {
+		return false, err
+	}
+	langs := strings.Split(item.Lang, ",") // a -lang chain
+	costly := false
+	res := prefetchWord(item.Word, langs, func(req Req) error {
+		_, err := getCachedResp(req)
+		if costly = err != nil || refresh; costly && !prefetchAllowed(share) {
+			return errShareUsed
+		}
+		return nil
+	}, func(req Req, res batchResult) {
+		if costly && res.Listed {
+			notePrefetch() // only once it's been spent, so failures don't use up the share
+		}
+	})
+	if res.Err == errShareUsed {
+		return true, res.Err // leave it queued; the languages done so far are cached
+	}
+	if res.Err != nil {
+		msgf("could not prefetch `%v`: %v\n", item.Word, res.Err)
+		if errors.Is(res.Err, errQuota) {
+			return true, res.Err
+		}
+	}
+	return true, dequeue(item, res.Err == nil)
+}
+
+// drainQueue prefetches queued words until the queue is empty or we may not spend any more today.
+func drainQueue() {
+	n := 0
+	for {
+		ok, err := prefetchOne()
+		if err != nil {
+			msg("stopping prefetch:", err)
+			break
+		}
+		if !ok {
+			break
+		}
+		n++
+	}
+	msg("worked through", n, "queued words")
+}
+
+// prefetchForever works through the queue in the background, giving way to any running lookups.
+func prefetchForever() {
+	for {
+		for atomic.LoadInt32(&lookupsRunning) > 0 {
+			time.Sleep(100 * time.Millisecond)
+		}
+		ok, err := prefetchOne()
+		switch {
+		case errors.Is(err, errShareUsed) || errors.Is(err, errQuota):
+			time.Sleep(10 * time.Minute) // try again later; maybe it's tomorrow
+		case err != nil:
+			msg("prefetch error:", err)
+			time.Sleep(time.Minute)
+		case !ok:
+			time.Sleep(time.Minute) // nothing queued; maybe something will be
+		default:
+			time.Sleep(time.Second) // leave the network to the user
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSortQueue(t *testing.T) {
+	items := []queueItem{
+		{Word: "a", Seq: 0, Freq: 1, Fails: 1},
+		{Word: "b", Seq: 1, Freq: 1},
+		{Word: "c", Seq: 2, Freq: 3},
+		{Word: "d", Seq: 3, Freq: 2},
+	}
+	for _, tc := range []struct {
+		order string
+		want  []string
+	}{
+		{"list", []string{"b", "c", "d", "a"}},
+		{"freq", []string{"c", "d", "b", "a"}},
+	} {
+		sorted := append([]queueItem{}, items...)
+		sortQueue(sorted, tc.order)
+		var got []string
+		for _, item := range sorted {
+			got = append(got, item.Word)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("sortQueue(%s) = %q, want %q", tc.order, got, tc.want)
+		}
+	}
+}
+
+func TestDequeue(t *testing.T) {
+	defer useFixtures(t)()
+	if n, err := enqueueWords([]string{"Hund", "Katze", "hund"}, "de"); err != nil || n != 2 {
+		t.Fatalf("enqueueWords = %d %v, want 2 new", n, err)
+	}
+	katze := queueItem{Word: "katze", Lang: "de"}
+	for i := 0; i < maxPrefetchFails; i++ {
+		if err := dequeue(katze, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := dequeue(queueItem{Word: "hund", Lang: "de"}, true); err != nil {
+		t.Fatal(err)
+	}
+	if q, err := loadQueue(); err != nil || len(q.Items) != 0 {
+		t.Errorf("after dequeueing everything, queue is %+v %v", q, err)
+	}
+}
+
+func TestPrefetchShare(t *testing.T) {
+	defer useFixtures(t)()
+	defer func(s float64) { *prefetchShare = s }(*prefetchShare)
+	*prefetchShare = 1
+	if _, err := enqueueWords([]string{"ich", "nicht aufgenommen"}, "de"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := prefetchOne(); !ok || err != nil {
+		t.Fatalf("prefetching ich: %v %v", ok, err)
+	}
+	if u := loadUsage(); u.Prefetched != 1 {
+		t.Errorf("after prefetching ich, %d prefetched, want 1", u.Prefetched)
+	}
+	prefetchOne() // isn't in the fixtures, so fails
+	if u := loadUsage(); u.Prefetched != 1 {
+		t.Errorf("a failed prefetch was counted: %d prefetched, want 1", u.Prefetched)
+	}
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// pronInfo is how a word is pronounced on paper, from the files indexed by `forvosay import`.
+type pronInfo struct {
+	IPA   string `json:",omitempty"`
+	Roman string `json:",omitempty"` // pinyin, jyutping, romaji, ...
+}
+
+func (p pronInfo) String() string {
+	var s []string
+	if p.IPA != "" {
+		s = append(s, p.IPA)
+	}
+	if p.Roman != "" {
+		s = append(s, p.Roman)
+	}
+	return strings.Join(s, "  ")
+}
+
+// pronIndex is what's been imported, by language and (normalized) word.
+var pronIndex struct {
+	sync.Mutex
+	langs map[string]map[string]pronInfo
+}
+
+func pronFname(lang string) string {
+	return cacheDir + "/.pron/" + lang + ".tsv"
+}
+
+// loadPron reads the index for lang; a missing index is just empty.
+func loadPron(lang string) (map[string]pronInfo, error) {
+	m := map[string]pronInfo{}
+	f, err := os.Open(pronFname(lang))
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return m, err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fs := strings.Split(sc.Text(), "\t")
+		if len(fs) == 3 {
+			m[fs[0]] = pronInfo{fs[1], fs[2]}
+		}
+	}
+	return m, sc.Err()
+}
+
+func savePron(lang string, m map[string]pronInfo) error {
+	words := make([]string, 0, len(m))
+	for w := range m {
+		words = append(words, w)
+	}
+	sort.Strings(words)
+	var b strings.Builder
+	for _, w := range words {
+		fmt.Fprintf(&b, "%s\t%s\t%s\n", w, m[w].IPA, m[w].Roman)
+	}
+	if err := os.MkdirAll(filepath.Dir(pronFname(lang)), 0777); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(pronFname(lang), []byte(b.String()), 0666)
+}
+
+// pronOf is the imported ipa/romanization of req's word, if any.
+func pronOf(req Req) pronInfo {
+	pronIndex.Lock()
+	defer pronIndex.Unlock()
+	if pronIndex.langs == nil {
+		pronIndex.langs = map[string]map[string]pronInfo{}
+	}
+	m, ok := pronIndex.langs[req.LangCode]
+	if !ok {
+		var err error
+		if m, err = loadPron(req.LangCode); err != nil {
+			msg("warning: could not read ipa/romanization index:", err)
+		}
+		pronIndex.langs[req.LangCode] = m
+	}
+	return m[req.Word]
+}
+
+// pronImport collects entries from the files being imported, by language.
+type pronImport map[string]map[string]pronInfo
+
+func (pi pronImport) add(lang, word string, p pronInfo) {
+	p.IPA = strings.Join(strings.Fields(p.IPA), " ") // no tabs or newlines, for the index
+	p.Roman = strings.Join(strings.Fields(p.Roman), " ")
+	if word == "" || p == (pronInfo{}) || !wantLang(lang) {
+		return
+	}
+	if pi[lang] == nil {
+		pi[lang] = map[string]pronInfo{}
+	}
+	word = normalizeWord(word, lang)
+	old := pi[lang][word]
+	if old.IPA == "" {
+		old.IPA = p.IPA
+	}
+	if old.Roman == "" {
+		old.Roman = p.Roman
+	}
+	pi[lang][word] = old
+}
+
+// wantLang says whether to import lang: all of them, unless -lang was given.
+func wantLang(lang string) bool {
+	chain := langChain()
+	return len(chain) == 0 || contains(chain, lang)
+}
+
+// kaikkiEntry is the part of a kaikki.org (wiktextract) jsonl line that we use.
+type kaikkiEntry struct {
+	Word     string
+	LangCode string `json:"lang_code"`
+	Sounds   []struct {
+		IPA    string
+		ZhPron string `json:"zh-pron"`
+		Tags   []string
+	}
+	Forms []struct {
+		Form string
+		Tags []string
+	}
+}
+
+func (pi pronImport) readKaikki(fname string) error {
+	r, err := openDictFile(fname)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 16<<20)
+	for sc.Scan() {
+		var e kaikkiEntry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			return fmt.Errorf("%s: %v", fname, err)
+		}
+		for _, s := range e.Sounds {
+			// chinese entries have both mandarin and cantonese, which forvo keeps apart
+			lang := e.LangCode
+			if lang == "zh" && contains(s.Tags, "Cantonese") {
+				lang = "yue"
+			}
+			if lang == "zh" && s.IPA != "" && !contains(s.Tags, "Mandarin") {
+				continue
+			}
+			pi.add(lang, e.Word, pronInfo{IPA: s.IPA})
+			switch {
+			case contains(s.Tags, "Pinyin"):
+				pi.add("zh", e.Word, pronInfo{Roman: s.ZhPron})
+			case contains(s.Tags, "Jyutping"):
+				pi.add("yue", e.Word, pronInfo{Roman: s.ZhPron})
+			}
+		}
+		for _, f := range e.Forms {
+			if contains(f.Tags, "romanization") {
+				pi.add(e.LangCode, e.Word, pronInfo{Roman: f.Form})
+			}
+		}
+	}
+	return sc.Err()
+}
+
+// readCedict reads a CC-CEDICT or CC-Canto file: "Trad Simp [pin1 yin1] {jyut6 ping3} /gloss/" lines.
+func (pi pronImport) readCedict(fname string) error {
+	r, err := openDictFile(fname)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		fs := strings.SplitN(line, " ", 3)
+		if len(fs) < 3 {
+			continue
+		}
+		pinyin := between(fs[2], "[", "]")
+		jyutping := between(fs[2], "{", "}")
+		for _, w := range fs[:2] {
+			pi.add("zh", w, pronInfo{Roman: pinyin})
+			pi.add("yue", w, pronInfo{Roman: jyutping})
+		}
+	}
+	return sc.Err()
+}
+
+// between is the text in s between the first open and the following close, if any.
+func between(s, open, close string) string {
+	i := strings.Index(s, open)
+	if i < 0 {
+		return ""
+	}
+	s = s[i+len(open):]
+	j := strings.Index(s, close)
+	if j < 0 {
+		return ""
+	}
+	return s[:j]
+}
+
+func runImport(args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fatal("usage: forvosay [-lang <lang>] import <kaikki.jsonl | cedict.txt>...")
+	}
+	pi := pronImport{}
+	indexed := false
+	for _, fname := range fs.Args() {
+		var err error
+		if ext := filepath.Ext(strings.TrimSuffix(fname, ".gz")); ext == ".jsonl" || ext == ".json" {
+			err = pi.readKaikki(fname)
+			if err == nil && !strings.HasSuffix(fname, ".gz") { // also index the definitions, for -define
+				var n int
+				if n, err = indexWikt(fname); err == nil {
+					msgf("%s: %d entries indexed for -define\n", fname, n)
+					indexed = true
+				}
+			}
+		} else {
+			err = pi.readCedict(fname)
+		}
+		if err != nil {
+			fatal("could not import:", err)
+		}
+	}
+	if len(pi) == 0 && !indexed {
+		fatal("found nothing to import")
+	}
+	for lang, words := range pi {
+		m, err := loadPron(lang)
+		if err != nil {
+			fatal("could not read ipa/romanization index:", err)
+		}
+		for w, p := range words {
+			old := m[w]
+			if p.IPA == "" {
+				p.IPA = old.IPA
+			}
+			if p.Roman == "" {
+				p.Roman = old.Roman
+			}
+			m[w] = p
+		}
+		if err := savePron(lang, m); err != nil {
+			fatal("could not save ipa/romanization index:", err)
+		}
+		msgf("%s: %d words (%d in all)\n", lang, len(words), len(m))
+	}
+}
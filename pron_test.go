@@ -0,0 +1,71 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadPron(t *testing.T) {
+	defer useFixtures(t)()
+	if m, err := loadPron("de"); err != nil || len(m) != 0 {
+		t.Errorf("with no index, loadPron = %v %v, want empty", m, err)
+	}
+	want := map[string]pronInfo{"hund": {IPA: "hʊnt"}, "電腦": {Roman: "din6 nou5"}, "ich": {"ɪç", "ich"}}
+	if err := savePron("de", want); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.OpenFile(pronFname("de"), os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("not enough fields\n\n")
+	f.Close()
+	if got, err := loadPron("de"); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("loadPron = %v %v, want %v", got, err, want)
+	}
+}
+
+func TestPronImport(t *testing.T) {
+	defer useFixtures(t)()
+	defer func(l string) { *lang = l }(*lang)
+	*lang = ""
+	dir, err := ioutil.TempDir("", "forvosay-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cedict := filepath.Join(dir, "cedict.txt")
+	kaikki := filepath.Join(dir, "kaikki.jsonl")
+	if err := ioutil.WriteFile(cedict, []byte("# comment\n電腦 电脑 [dian4 nao3] {din6 nou5} /computer/\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(kaikki, []byte(`{"word":"Hund","lang_code":"de","sounds":[{"ipa":"/hʊnt/"}]}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pi := pronImport{}
+	if err := pi.readCedict(cedict); err != nil {
+		t.Fatal(err)
+	}
+	if err := pi.readKaikki(kaikki); err != nil {
+		t.Fatal(err)
+	}
+	want := pronImport{
+		"zh":  {"電腦": {Roman: "dian4 nao3"}, "电脑": {Roman: "dian4 nao3"}},
+		"yue": {"電腦": {Roman: "din6 nou5"}, "电脑": {Roman: "din6 nou5"}},
+		"de":  {"hund": {IPA: "/hʊnt/"}},
+	}
+	if !reflect.DeepEqual(pi, want) {
+		t.Errorf("imported %v, want %v", pi, want)
+	}
+	if err := savePron("yue", pi["yue"]); err != nil {
+		t.Fatal(err)
+	}
+	pronIndex.langs = nil
+	defer func() { pronIndex.langs = nil }()
+	if got := pronOf(Req{"電腦", "yue"}); got.Roman != "din6 nou5" {
+		t.Errorf("pronOf(電腦) = %v", got)
+	}
+}
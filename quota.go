@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+var quota = flag.Int("quota", 500, "`max` number of requests to make to the forvo api per day, per key (the free api key allows 500); <= 0 for no limit")
+
+var errQuota = errors.New("daily forvo api quota used up for every key (see -quota)")
+
+// usage is what we've spent of today's quota; kept in the cache dir so it's shared between runs.
+type usage struct {
+	Day        string // UTC, YYYY-MM-DD
+	Requests   int
+	Prefetched int                  // requests made for the prefetch queue (also counted in Requests)
+	Keys       map[string]*keyUsage // by keyID
+}
+
+type keyUsage struct {
+	Requests int
+	Refused  bool // forvo said no; don't use it again today
+}
+
+func (u *usage) key(key string) *keyUsage {
+	if u.Keys == nil {
+		u.Keys = map[string]*keyUsage{}
+	}
+	id := keyID(key)
+	if u.Keys[id] == nil {
+		u.Keys[id] = &keyUsage{}
+	}
+	return u.Keys[id]
+}
+
+func (ku *keyUsage) ok() bool {
+	return !ku.Refused && (*quota <= 0 || ku.Requests < *quota)
+}
+
+var usageMu sync.Mutex
+
+func usageFname() string {
+	return cacheDir + "/.usage.json"
+}
+
+func today() string {
+	return time.Now().UTC().Format("2006-01-02")
+}
+
+func loadUsage() usage {
+	u := usage{Day: today()}
+	buf, err := ioutil.ReadFile(usageFname())
+	if err != nil {
+		return u
+	}
+	var saved usage
+	if err := json.Unmarshal(buf, &saved); err != nil || saved.Day != u.Day {
+		return u
+	}
+	return saved
+}
+
+func saveUsage(u usage) error {
+	buf, err := json.Marshal(&u)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(cacheDir, 0777); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(usageFname(), buf, 0666)
+}
+
+// spendQuota picks a key with requests left today and records one request against it, or returns errQuota.
+func spendQuota() (string, error) {
+	if *replay != "" && len(apiKeys) > 0 {
+		return apiKeys[0], nil // replayed responses don't cost anything
+	}
+	usageMu.Lock()
+	defer usageMu.Unlock()
+	u := loadUsage()
+	for _, key := range apiKeys {
+		ku := u.key(key)
+		if !ku.ok() {
+			continue
+		}
+		ku.Requests++
+		u.Requests++
+		if err := saveUsage(u); err != nil {
+			msg("warning: could not save api usage:", err)
+		}
+		return key, nil
+	}
+	return "", errQuota
+}
+
+// refuseKey marks key as unusable for the rest of the day.
+func refuseKey(key string) {
+	usageMu.Lock()
+	defer usageMu.Unlock()
+	u := loadUsage()
+	u.key(key).Refused = true
+	if err := saveUsage(u); err != nil {
+		msg("warning: could not save api usage:", err)
+	}
+}
+
+// totalQuota is the number of requests all our keys together allow per day; < 0 if there's no limit.
+func totalQuota() int {
+	if *quota <= 0 {
+		return -1
+	}
+	return *quota * len(apiKeys)
+}
+
+// quotaLeft is the number of requests we can still make today; < 0 if there's no limit.
+func quotaLeft() int {
+	if *quota <= 0 {
+		return -1
+	}
+	usageMu.Lock()
+	defer usageMu.Unlock()
+	u := loadUsage()
+	n := 0
+	for _, key := range apiKeys {
+		if ku := u.key(key); ku.ok() {
+			n += *quota - ku.Requests
+		}
+	}
+	return n
+}
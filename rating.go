@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// ratings are our own 1-5 ratings of pronunciations, by forvo id, kept in cacheDir/.ratings.json.
+var ratingsMu sync.Mutex
+
+func ratingsFname() string {
+	return cacheDir + "/.ratings.json"
+}
+
+func loadRatings() map[string]int {
+	r := map[string]int{}
+	buf, err := ioutil.ReadFile(ratingsFname())
+	if err != nil {
+		return r
+	}
+	json.Unmarshal(buf, &r)
+	return r
+}
+
+// allRatings are all our ratings, by forvo id (as a string).
+func allRatings() map[string]int {
+	ratingsMu.Lock()
+	defer ratingsMu.Unlock()
+	return loadRatings()
+}
+
+func saveRating(item Pronunciation, rating int) error {
+	ratingsMu.Lock()
+	defer ratingsMu.Unlock()
+	r := loadRatings()
+	r[strconv.FormatInt(item.Id, 10)] = rating
+	buf, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(cacheDir, 0777); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(ratingsFname(), buf, 0666)
+}
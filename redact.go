@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+var diag = flag.Bool("diag", false, "don't look anything up; show the request that would be sent to forvo for -word (with the api key masked), and which key it would use")
+
+// redact masks every api key in s; everything we print goes through here.
+func redact(s string) string {
+	for _, key := range apiKeys {
+		s = strings.Replace(s, key, maskKey(key), -1)
+	}
+	return s
+}
+
+func maskKey(key string) string {
+	return "<key " + keyID(key) + ">"
+}
+
+// redactedError hides api keys in the message of err (which is still there for errors.Is).
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string { return e.msg }
+func (e *redactedError) Unwrap() error { return e.err }
+
+func redactErr(err error) error {
+	if err == nil {
+		return nil
+	}
+	if s := redact(err.Error()); s != err.Error() {
+		return &redactedError{s, err}
+	}
+	return err
+}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// confirmNoSSL makes sure the user really means to send their api key in the clear.
+func confirmNoSSL() error {
+	if os.Getenv("FORVOSAY_NOSSL") == "yes" {
+		return nil
+	}
+	if !isTerminal(os.Stdin) {
+		return errors.New("-nossl sends your api key unencrypted; set FORVOSAY_NOSSL=yes in your environment if you mean it")
+	}
+	fmt.Fprint(os.Stderr, "-nossl sends your api key unencrypted, for anyone on the network to see; type yes to continue: ")
+	s, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil || strings.TrimSpace(s) != "yes" {
+		return errors.New("not using -nossl")
+	}
+	return nil
+}
+
+func runDiag(req Req) {
+	key, src := "", ""
+	u := loadUsage()
+	for _, k := range apiKeys {
+		if u.key(k).ok() {
+			key = k
+			break
+		}
+	}
+	if key == "" {
+		msg("no api key has requests left today")
+		key = apiKeys[0]
+	}
+	if strings.Contains(os.Getenv("FORVO_API_KEY"), key) {
+		src = "FORVO_API_KEY"
+	} else {
+		src = *keyFile
+	}
+	msg("GET " + wordURL(key, req))
+	msg("api key:", maskKey(key), "from", src)
+	for _, k := range apiKeys {
+		ku := u.key(k)
+		status := ""
+		if ku.Refused {
+			status = " (refused by forvo today)"
+		}
+		if *quota > 0 {
+			msgf("  %s: %d of %d requests used today%s\n", maskKey(k), ku.Requests, *quota, status)
+		} else {
+			msgf("  %s: %d requests made today%s\n", maskKey(k), ku.Requests, status)
+		}
+	}
+	if !strings.HasPrefix(apiAddr(), "https://") {
+		msg("ssl: NO, the key would be sent in the clear")
+	} else {
+		msg("ssl: yes")
+	}
+}
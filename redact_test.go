@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	defer func(keys []string) { apiKeys = keys }(apiKeys)
+	apiKeys = []string{"secret1", "s3cr3t2"}
+	k1, k2 := maskKey("secret1"), maskKey("s3cr3t2")
+	if k1 == k2 || !strings.HasPrefix(k1, "<key ") || strings.Contains(k1, "secret1") {
+		t.Fatalf("masked keys are %q and %q", k1, k2)
+	}
+	for _, tc := range []struct{ in, want string }{
+		{"nothing to hide", "nothing to hide"},
+		{"/key/secret1/format/json", "/key/" + k1 + "/format/json"},
+		{"secret1 and s3cr3t2 and secret1", k1 + " and " + k2 + " and " + k1},
+		{"", ""},
+	} {
+		if got := redact(tc.in); got != tc.want {
+			t.Errorf("redact(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+	err := errors.New("GET /key/secret1/: timeout")
+	if got := redactErr(err); strings.Contains(got.Error(), "secret1") || !errors.Is(got, err) {
+		t.Errorf("redactErr(%v) = %v", err, got)
+	}
+	if redactErr(nil) != nil {
+		t.Error("redactErr(nil) isn't nil")
+	}
+}
+
+func TestRunDiag(t *testing.T) {
+	defer useFixtures(t)()
+	defer func(out io.Writer) { msgOut = out }(msgOut)
+	var b bytes.Buffer
+	msgOut = &b
+	apiKeys = []string{"secret1"}
+	runDiag(Req{"ich", "de"})
+	if s := b.String(); strings.Contains(s, "secret1") || !strings.Contains(s, maskKey("secret1")) || !strings.Contains(s, "word/ich") {
+		t.Errorf("runDiag printed:\n%s", s)
+	}
+}
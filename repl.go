@@ -0,0 +1,372 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/tevino/abool"
+)
+
+var keys = flag.Bool("keys", false, "act on single key presses on stdin, without waiting for enter (press h for which)")
+
+// control is what input on stdin can do to the clipboard loop in lookupForever.
+type control struct {
+	word        atomic.Value // string: what was looked up last
+	suggestions atomic.Value // []string: offered as s1, s2...
+	pickNum     int32        // play this pronunciation (numbered from 1) when repeating
+	repeat      *abool.AtomicBool
+	wakeup      chan struct{}                                 // tells the clipboard loop to look at repeat now
+	start       func(s string, r bool, p int) <-chan struct{} // look s up (again, if r), closing the chan when done
+
+	mu      sync.Mutex
+	item    Pronunciation // the pronunciation played last
+	listed  []int         // the indexes of those listed with it, per -country (none if nothing's played yet)
+	history []string      // what's been looked up, oldest first
+}
+
+func newControl() *control {
+	return &control{repeat: abool.New(), wakeup: make(chan struct{}, 1)}
+}
+
+func (c *control) lastWord() string {
+	w, _ := c.word.Load().(string)
+	return w
+}
+
+// looked records a lookup, for :history.
+func (c *control) looked(s string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if n := len(c.history); n == 0 || c.history[n-1] != s {
+		c.history = append(c.history, s)
+	}
+}
+
+func (c *control) playing(item Pronunciation, listed []int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.item, c.listed = item, listed
+}
+
+// repeatLast has the clipboard loop look up the last word again.
+func (c *control) repeatLast() {
+	c.repeat.Set()
+	select {
+	case c.wakeup <- struct{}{}:
+	default:
+	}
+}
+
+// replay plays pronunciation num of the last word again.
+func (c *control) replay(num int) {
+	atomic.StoreInt32(&c.pickNum, int32(num))
+	c.repeatLast()
+}
+
+// nextSpeaker replays the pronunciation listed after (or with dir < 0, before) the one played last.
+func (c *control) nextSpeaker(dir int) {
+	c.mu.Lock()
+	index, listed := c.item.Index, c.listed
+	c.mu.Unlock()
+	n := len(listed)
+	if n == 0 {
+		msg("nothing played yet")
+		return
+	}
+	i := 0
+	for j, x := range listed {
+		if x == index {
+			i = j
+		}
+	}
+	c.replay(listed[(i+dir+n)%n] + 1)
+}
+
+// rate gives the pronunciation played last our own rating.
+func (c *control) rate(rating int) {
+	c.mu.Lock()
+	item, listed := c.item, c.listed
+	c.mu.Unlock()
+	if len(listed) == 0 {
+		msg("nothing played yet")
+		return
+	}
+	if err := saveRating(item, rating); err != nil {
+		msg("could not save rating:", err)
+		return
+	}
+	msgf("rated %s's `%s` %d/5\n", item.Username, item.Word, rating)
+}
+
+const keysHelp = `keys (with -keys):
+  space, enter   play the last word again
+  1-9            play that pronunciation of the last word
+  n, p           play the next or previous speaker's pronunciation
+  s              stop playing
+  r then 1-5     rate the pronunciation played last
+  d, y, g, c, f  as on a line by themselves (see :help)
+  /              type a word to look up
+  :              type a command (see :help)`
+
+// handleKey acts on a key pressed on stdin, with -keys.
+func (c *control) handleKey(lr *lineReader) error {
+	k, err := lr.readKey()
+	if err != nil {
+		return err
+	}
+	switch {
+	case k == ' ' || k == '\n' || k == '\r':
+		c.repeatLast()
+	case k >= '1' && k <= '9':
+		if c.lastWord() != "" {
+			c.replay(int(k - '0'))
+		}
+	case k == 'n':
+		c.nextSpeaker(1)
+	case k == 'p':
+		c.nextSpeaker(-1)
+	case k == 's':
+		stopPlayback()
+	case k == 'r':
+		msgf("rating (1-5)? ")
+		k, err := lr.readKey()
+		if err != nil {
+			return err
+		}
+		msg()
+		if k < '1' || k > '5' {
+			msg("not rated")
+			return nil
+		}
+		c.rate(int(k - '0'))
+	case strings.ContainsRune("dygcf", k):
+		if c.lastWord() != "" { // otherwise handleInput would look up the letter
+			c.handleInput(string(k))
+		}
+	case k == '/' || k == ':':
+		s, err := lr.readLine(string(k))
+		if err != nil {
+			return err
+		}
+		if s = strings.TrimSpace(s); s == "" {
+			return nil
+		}
+		if k == ':' {
+			s = ":" + s
+		}
+		c.handleInput(s)
+	case k == 'h' || k == '?':
+		msg(keysHelp)
+	}
+	return nil
+}
+
+const replHelp = `on stdin:
+  <enter>        play the last word again
+  N              play pronunciation #N of the last word
+  :next, :prev   play the next or previous speaker's pronunciation
+  sN             look up suggestion #N
+  d, y, g, c     look the last word up in the mac dictionary, yandex/google images or cantonese.org
+  f              show the full -define entry for the last word
+  :history [N]   list the words looked up so far, or look up #N again
+  :<flag> <val>  change an option, e.g. :lang fr, :n 3, :top 10, :country MX
+  :<flag>        toggle an on/off option (e.g. :canto), or show the value of another
+  :keys          act on single keys instead of lines (see h then)
+  :help          this
+  anything else  look it up`
+
+// handleInput acts on a line typed on stdin.
+func (c *control) handleInput(s string) {
+	s = strings.TrimSpace(s)
+	w := c.lastWord()
+	if strings.HasPrefix(s, ":") {
+		c.command(strings.Fields(s[1:]))
+		return
+	}
+	if s == "" {
+		c.repeatLast()
+		return
+	}
+	if n, err := strconv.Atoi(s); err == nil && n > 0 {
+		if w != "" {
+			c.replay(n)
+		}
+		return
+	}
+	if n, err := strconv.Atoi(strings.TrimPrefix(s, "s")); err == nil && s[0] == 's' {
+		sugg, _ := c.suggestions.Load().([]string)
+		if n < 1 || n > len(sugg) {
+			msg("no suggestion", s)
+			return
+		}
+		c.start(sugg[n-1], false, 0)
+		return
+	}
+	if w != "" {
+		switch s {
+		case "d":
+			lookupDict(w)
+			return
+		case "y":
+			lookupWebYandexImages(w)
+			return
+		case "g":
+			lookupWebGoogleImages(firstLang(), w)
+			return
+		case "c":
+			lookupWebCanto(w)
+			return
+		case "f":
+			langs, _, ok := routeLangs(w)
+			if !ok {
+				langs = langChain()
+			}
+			printDefinitions(w, langs, true)
+			return
+		}
+	}
+	c.start(s, false, 0)
+}
+
+func (c *control) command(args []string) {
+	if len(args) == 0 {
+		msg(replHelp)
+		return
+	}
+	switch args[0] {
+	case "help", "?":
+		msg(replHelp)
+	case "next":
+		c.nextSpeaker(1)
+	case "prev":
+		c.nextSpeaker(-1)
+	case "history":
+		c.mu.Lock()
+		hist := append([]string{}, c.history...)
+		c.mu.Unlock()
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 || n > len(hist) {
+				msg("no history entry", args[1])
+				return
+			}
+			c.start(hist[n-1], false, 0)
+			return
+		}
+		for i, h := range hist {
+			msgf("%3d. %s\n", i+1, h)
+		}
+	default:
+		setOption(args[0], strings.Join(args[1:], " "))
+	}
+}
+
+// boolFlag is the interface of the flag package's on/off flags.
+type boolFlag interface {
+	flag.Value
+	IsBoolFlag() bool
+}
+
+// optionsMu guards the flags, which setOption changes while lookups (and prefetching) read them; they
+// hold it just long enough to copy what they need.
+var optionsMu sync.RWMutex
+
+// startupOnly are the options that only mean anything at startup, or (-nossl) have to be confirmed then.
+var startupOnly = map[string]bool{
+	"batch": true, "batchfmt": true, "col": true, "diag": true, "enqueue": true, "j": true, "keyfile": true,
+	"nossl": true, "prefetch": true, "selection": true, "stdin": true, "sync": true, "tui": true, "word": true,
+}
+
+// setOption changes a flag while running; with no value it toggles a bool flag or shows the value.
+func setOption(name, val string) {
+	f := flag.Lookup(name)
+	if f == nil {
+		var names []string
+		flag.VisitAll(func(f *flag.Flag) { names = append(names, f.Name) })
+		sort.Strings(names)
+		msgf("no option -%s; try one of: %s\n", name, strings.Join(names, " "))
+		return
+	}
+	if val == "" {
+		b, ok := f.Value.(boolFlag)
+		if !ok || !b.IsBoolFlag() {
+			msgf("-%s is %q\n", name, f.Value.String())
+			return
+		}
+		val = strconv.FormatBool(f.Value.String() != "true")
+	}
+	if startupOnly[name] {
+		msgf("-%s can only be given at startup\n", name)
+		return
+	}
+	if err := changeOption(name, val); err != nil {
+		msg(err)
+		return
+	}
+	// files are read again when next needed (which takes optionsMu, so not while it's held)
+	switch name {
+	case "define":
+		resetDictionaries()
+	case "segdict":
+		resetSegDict()
+	case "convtab":
+		resetConvTabs()
+	case "lemmas":
+		resetLemmas()
+	}
+	msgf("-%s is now %q\n", name, f.Value.String())
+}
+
+// changeOption sets a flag, and redoes whatever was made of it at startup; if that fails, the flag is
+// put back.
+func changeOption(name, val string) error {
+	optionsMu.Lock()
+	defer optionsMu.Unlock()
+	old := flag.Lookup(name).Value.String()
+	if err := flag.Set(name, val); err != nil {
+		flag.Set(name, old) // the flag package can leave a bad value half-set
+		return fmt.Errorf("bad value for -%s: %v", name, err)
+	}
+	// some options are checked and parsed once at startup
+	reparse := func() error {
+		switch name {
+		case "lang":
+			return checkLangs()
+		case "format":
+			return parseFormat()
+		case "route":
+			return parseRoutes()
+		case "yt":
+			if *yt != "" && (*yt)[0] == '-' {
+				return errors.New("bad -yt: " + *yt + " -- expected <LANG>-<LANG>")
+			}
+		case "prefetchorder":
+			if *prefetchOrder != "list" && *prefetchOrder != "freq" {
+				return errors.New("bad -prefetchorder: " + *prefetchOrder + " -- expected list or freq")
+			}
+		case "chrome":
+			*chrome = "," + strings.Trim(*chrome, ",") + ","
+		case "json":
+			msgOut = os.Stdout
+			if *jsonOut {
+				msgOut = os.Stderr
+			}
+		case "record", "replay":
+			useTransport()
+		}
+		return nil
+	}
+	if err := reparse(); err != nil {
+		flag.Set(name, old)
+		reparse()
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSetOption(t *testing.T) {
+	defer func(n int, c bool) { *numSay, *canto = n, c }(*numSay, *canto)
+	setOption("n", "3")
+	if *numSay != 3 {
+		t.Errorf(":n 3 left -n at %d", *numSay)
+	}
+	setOption("n", "lots")
+	if *numSay != 3 {
+		t.Errorf(":n lots changed -n to %d", *numSay)
+	}
+	*canto = false
+	setOption("canto", "")
+	if !*canto {
+		t.Error(":canto didn't turn -canto on")
+	}
+	setOption("canto", "")
+	if *canto {
+		t.Error(":canto again didn't turn -canto off")
+	}
+	setOption("nossl", "")
+	if *nossl {
+		t.Error(":nossl turned -nossl on without asking")
+	}
+}
+
+func TestSetOptionReparses(t *testing.T) {
+	defer func(c string, j bool, out io.Writer) { *chrome, *jsonOut, msgOut = c, j, out }(*chrome, *jsonOut, msgOut)
+	setOption("chrome", "gi,yi")
+	if !strings.Contains(*chrome, ",gi,") || !strings.Contains(*chrome, ",yi,") {
+		t.Errorf(":chrome gi,yi left -chrome at %q", *chrome)
+	}
+	setOption("json", "true")
+	if msgOut != os.Stderr {
+		t.Error(":json didn't send messages to stderr")
+	}
+	setOption("json", "false")
+	if msgOut != os.Stdout {
+		t.Error(":json false didn't send messages back to stdout")
+	}
+	setOption("yt", "-en")
+	if *yt != "" {
+		t.Errorf(":yt -en set -yt to %q", *yt)
+	}
+}
+
+func TestOptionsCopied(t *testing.T) {
+	defer func(n int, c string) { *numSay, *country = n, c }(*numSay, *country)
+	*numSay, *country = 1, ""
+	o := currentOptions()
+	setOption("n", "4")
+	setOption("country", "MX")
+	if o.numSay != 1 || o.country != "" {
+		t.Errorf(":set changed options already copied: -n %d, -country %q", o.numSay, o.country)
+	}
+	if o = currentOptions(); o.numSay != 4 || o.country != "MX" {
+		t.Errorf("options copied after :set: -n %d, -country %q", o.numSay, o.country)
+	}
+}
+
+func TestNextSpeaker(t *testing.T) {
+	c := newControl()
+	for _, tc := range []struct {
+		num    int
+		listed []int
+		dir    int
+		want   int
+	}{
+		{1, []int{0, 1, 2}, 1, 2},
+		{3, []int{0, 1, 2}, 1, 1},
+		{1, []int{0, 1, 2}, -1, 3},
+		{2, []int{0, 1, 2}, -1, 1},
+		{3, []int{2, 4, 7}, 1, 5}, // with -country, only some are listed
+		{8, []int{2, 4, 7}, 1, 3},
+		{3, []int{2, 4, 7}, -1, 8},
+	} {
+		c.playing(Pronunciation{Index: tc.num - 1}, tc.listed)
+		c.nextSpeaker(tc.dir)
+		if got := int(c.pickNum); got != tc.want || !c.repeat.IsSet() {
+			t.Errorf("after #%d of %v, nextSpeaker(%d) picked #%d, want #%d", tc.num, tc.listed, tc.dir, got, tc.want)
+		}
+	}
+}
+
+// typed is a lineReader that reads s, as if typed at a terminal.
+func typed(s string) *lineReader {
+	lr := &lineReader{tty: true, runes: make(chan rune, len(s))}
+	for _, r := range s {
+		lr.runes <- r
+	}
+	return lr
+}
+
+func TestHandleKeyNoWord(t *testing.T) {
+	c := newControl()
+	c.start = func(s string, r bool, p int) <-chan struct{} {
+		t.Errorf("looked up %q with nothing looked up yet", s)
+		return nil
+	}
+	for _, k := range "dygcf" {
+		if err := c.handleKey(typed(string(k))); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReadKeyEscape(t *testing.T) {
+	lr := typed("\x1b[A\x1b")
+	if k, err := lr.readKey(); err != nil || k != keyUp {
+		t.Errorf("ESC [ A read as %v %v, want keyUp", k, err)
+	}
+	go func() {
+		time.Sleep(2 * escapeWait)
+		lr.runes <- 'q'
+	}()
+	if k, err := lr.readKey(); err != nil || k != 'q' {
+		t.Errorf("ESC then q read as %q %v, want q", k, err)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+var route = flag.String("route", "", "pick the language of each clipboard lookup by its script: comma-separated `script=lang` pairs (lang may be a chain like yue|zh), on top of the defaults "+defaultRoutes+"; or just auto for the defaults")
+
+const defaultRoutes = "cyrillic=ru,kana=ja,han=zh,hangul=ko"
+
+// routes maps a script (lowercase unicode script name, or kana for hiragana and katakana) to a language chain.
+var routes map[string][]string
+
+func parseRoutes() error {
+	if *route == "" {
+		return nil
+	}
+	routes = map[string][]string{}
+	for _, spec := range []string{defaultRoutes, *route} {
+		for _, r := range strings.Split(spec, ",") {
+			r = strings.TrimSpace(r)
+			if r == "" || r == "auto" {
+				continue
+			}
+			i := strings.Index(r, "=")
+			if i < 0 {
+				return fmt.Errorf("bad -route %q: expected script=lang", r)
+			}
+			script := strings.ToLower(strings.TrimSpace(r[:i]))
+			if script != "kana" && scriptTable(script) == nil {
+				return fmt.Errorf("bad -route %q: unknown script %q", r, script)
+			}
+			var langs []string
+			for _, l := range strings.Split(r[i+1:], "|") {
+				if l = strings.TrimSpace(l); l != "" {
+					langs = append(langs, l)
+				}
+			}
+			routes[script] = langs
+		}
+	}
+	return nil
+}
+
+func scriptTable(name string) *unicode.RangeTable {
+	for n, t := range unicode.Scripts {
+		if strings.ToLower(n) == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// scriptOf says what script s is (mostly) written in: any kana makes it kana (Japanese mixes in han),
+// otherwise the script of most of its letters.
+func scriptOf(s string) string {
+	counts := map[string]int{}
+	for _, r := range s {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		if unicode.In(r, unicode.Hiragana, unicode.Katakana) {
+			return "kana"
+		}
+		for name, t := range unicode.Scripts {
+			if unicode.Is(t, r) {
+				counts[strings.ToLower(name)]++
+				break
+			}
+		}
+	}
+	var names []string
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	if len(names) == 0 {
+		return ""
+	}
+	return names[0]
+}
+
+// routeLangs picks the languages to look s up in, per -route; ok is false if s should just use -lang.
+func routeLangs(s string) (langs []string, script string, ok bool) {
+	optionsMu.RLock()
+	defer optionsMu.RUnlock()
+	if routes == nil {
+		return nil, "", false
+	}
+	script = scriptOf(s)
+	langs, ok = routes[script]
+	return langs, script, ok
+}
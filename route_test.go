@@ -0,0 +1,54 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestScriptOf(t *testing.T) {
+	for _, tc := range []struct{ s, want string }{
+		{"Hund", "latin"},
+		{"собака", "cyrillic"},
+		{"電腦", "han"},
+		{"食べる", "kana"},
+		{"日本語のテキスト", "kana"},
+		{"한국어", "hangul"},
+		{"USB-кабель", "cyrillic"},
+		{"123 !?", ""},
+		{"", ""},
+	} {
+		if got := scriptOf(tc.s); got != tc.want {
+			t.Errorf("scriptOf(%q) = %q, want %q", tc.s, got, tc.want)
+		}
+	}
+}
+
+func TestRouteLangs(t *testing.T) {
+	defer func(r string, rs map[string][]string) { *route, routes = r, rs }(*route, routes)
+	*route = "han=yue|zh, latin=de"
+	if err := parseRoutes(); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		s      string
+		langs  []string
+		script string
+		ok     bool
+	}{
+		{"電腦", []string{"yue", "zh"}, "han", true},
+		{"собака", []string{"ru"}, "cyrillic", true},
+		{"Hund", []string{"de"}, "latin", true},
+		{"ελληνικά", nil, "greek", false},
+	} {
+		langs, script, ok := routeLangs(tc.s)
+		if !reflect.DeepEqual(langs, tc.langs) || script != tc.script || ok != tc.ok {
+			t.Errorf("routeLangs(%q) = %q %q %v, want %q %q %v", tc.s, langs, script, ok, tc.langs, tc.script, tc.ok)
+		}
+	}
+	for _, bad := range []string{"han", "klingon=tlh"} {
+		*route = bad
+		if err := parseRoutes(); err == nil {
+			t.Errorf("-route %q wasn't refused", bad)
+		}
+	}
+}
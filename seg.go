@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/xml"
+	"flag"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+var segDictFiles = flag.String("segdict", "", "comma-separated CC-CEDICT, CC-Canto or JMdict `files` (optionally gzipped) to split Chinese and Japanese text into words with, for -sentences")
+
+// segDict is the set of words from the -segdict files.
+var segDict struct {
+	mu     sync.Mutex // held while loading or resetting
+	once   sync.Once
+	words  map[string]bool
+	maxLen int // in runes
+}
+
+// resetSegDict has the -segdict files read again when next needed.
+func resetSegDict() {
+	segDict.mu.Lock()
+	defer segDict.mu.Unlock()
+	segDict.once = sync.Once{}
+	segDict.words, segDict.maxLen = nil, 0
+}
+
+// segWords are the -segdict words and the length of the longest, read when first needed.
+func segWords() (map[string]bool, int) {
+	segDict.mu.Lock()
+	defer segDict.mu.Unlock()
+	segDict.once.Do(loadSegDict)
+	return segDict.words, segDict.maxLen
+}
+
+func loadSegDict() {
+	segDict.words = map[string]bool{}
+	optionsMu.RLock()
+	files := *segDictFiles
+	optionsMu.RUnlock()
+	if files == "" {
+		return
+	}
+	for _, fname := range strings.Split(files, ",") {
+		if err := readDictWords(strings.TrimSpace(fname), addSegWord); err != nil {
+			msg("warning: could not read -segdict file:", err)
+		}
+	}
+}
+
+func addSegWord(w string) {
+	if w == "" {
+		return
+	}
+	segDict.words[w] = true
+	if n := utf8.RuneCountInString(w); n > segDict.maxLen {
+		segDict.maxLen = n
+	}
+}
+
+// openDictFile opens a dictionary file, gunzipping it if it ends in .gz.
+func openDictFile(fname string) (io.ReadCloser, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(fname, ".gz") {
+		return f, nil
+	}
+	z, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{z, f}, nil
+}
+
+// readDictWords calls add with each headword of a CC-CEDICT/CC-Canto (text) or JMdict (xml) file.
+func readDictWords(fname string, add func(string)) error {
+	r, err := openDictFile(fname)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(512)
+	if strings.Contains(string(head), "<?xml") || strings.Contains(string(head), "<JMdict") {
+		return readJMdictWords(br, add)
+	}
+	sc := bufio.NewScanner(br)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		// Traditional Simplified [pin1 yin1] {jyut6 ping3} /gloss/
+		fs := strings.SplitN(line, " ", 3)
+		if len(fs) < 2 {
+			continue
+		}
+		add(fs[0])
+		add(fs[1])
+	}
+	return sc.Err()
+}
+
+func readJMdictWords(r io.Reader, add func(string)) error {
+	d := xml.NewDecoder(r)
+	d.Strict = false // JMdict uses entities from its DTD (&n; etc.), which we don't care about
+	var in string
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			in = t.Name.Local
+		case xml.EndElement:
+			in = ""
+		case xml.CharData:
+			if in == "keb" || in == "reb" {
+				add(strings.TrimSpace(string(t)))
+			}
+		}
+	}
+}
+
+func inSegDict(w string) bool {
+	words, _ := segWords()
+	return words[w]
+}
+
+func haveSegDict() bool {
+	words, _ := segWords()
+	return len(words) > 0
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana)
+}
+
+func hasCJK(s string) bool {
+	for _, r := range s {
+		if isCJK(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// segment splits text with no spaces into -segdict words, using as few words as possible (characters not
+// in the dictionary count as words of their own, but cost more).
+func segment(s string) []string {
+	words, maxLen := segWords()
+	rs := []rune(s)
+	n := len(rs)
+	const unknown = 2 // cost of a character that isn't a word on its own
+	cost := make([]int, n+1)
+	from := make([]int, n+1)
+	for i := 1; i <= n; i++ {
+		cost[i] = cost[i-1] + unknown
+		from[i] = i - 1
+		if words[string(rs[i-1])] {
+			cost[i] = cost[i-1] + 1
+		}
+		for j := i - 2; j >= 0 && i-j <= maxLen; j-- {
+			if words[string(rs[j:i])] && cost[j]+1 < cost[i] {
+				cost[i] = cost[j] + 1
+				from[i] = j
+			}
+		}
+	}
+	var seg []string
+	for i := n; i > 0; i = from[i] {
+		seg = append(seg, string(rs[from[i]:i]))
+	}
+	for i, j := 0, len(seg)-1; i < j; i, j = i+1, j-1 {
+		seg[i], seg[j] = seg[j], seg[i]
+	}
+	return seg
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSegment(t *testing.T) {
+	dir, err := ioutil.TempDir("", "forvosay-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fname := filepath.Join(dir, "cedict.txt")
+	dict := "# CC-CEDICT\n" +
+		"我 我 [wo3] /I; me/\n" +
+		"喜歡 喜欢 [xi3 huan5] /to like/\n" +
+		"電腦 电脑 [dian4 nao3] /computer/\n" +
+		"電 电 [dian4] /electricity/\n"
+	if err := ioutil.WriteFile(fname, []byte(dict), 0644); err != nil {
+		t.Fatal(err)
+	}
+	oldFiles := *segDictFiles
+	defer func() {
+		*segDictFiles = oldFiles
+		resetSegDict()
+	}()
+	*segDictFiles = fname
+	resetSegDict()
+	for _, tc := range []struct {
+		text string
+		want []string
+	}{
+		{"我喜歡電腦", []string{"我", "喜歡", "電腦"}},
+		{"我喜欢电脑", []string{"我", "喜欢", "电脑"}},
+		{"我很喜歡", []string{"我", "很", "喜歡"}},
+	} {
+		if got := segment(tc.text); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("segment(%q) = %q, want %q", tc.text, got, tc.want)
+		}
+	}
+	if got, want := tokenize("我喜歡 the 電腦!"), []string{"我", "喜歡", "the", "電腦"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tokenize = %q, want %q", got, want)
+	}
+}
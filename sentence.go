@@ -0,0 +1,134 @@
+package main
+
+import (
+	"flag"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+var sentences = flag.Bool("sentences", false, "pronounce sentences (4 or more words) word by word, skipping words already heard this session; words with no pronunciations are said with the -fallback voice, if any")
+var gap = flag.Duration("gap", 300*time.Millisecond, "with -sentences, pause this long between words")
+
+// tokenize splits a sentence into words: runs of letters, marks and digits, with apostrophes and
+// hyphens inside a word kept (l'homme, bien-être); Chinese and Japanese are split per -segdict.
+func tokenize(s string) []string {
+	var words []string
+	rs := []rune(s)
+	inWord := func(i int) bool {
+		r := rs[i]
+		if unicode.IsLetter(r) || unicode.IsMark(r) || unicode.IsDigit(r) {
+			return true
+		}
+		if r == '\'' || r == '’' || r == '-' {
+			return i > 0 && i+1 < len(rs) && unicode.IsLetter(rs[i-1]) && unicode.IsLetter(rs[i+1])
+		}
+		return false
+	}
+	start := -1
+	for i := range rs {
+		switch {
+		case inWord(i) && start < 0:
+			start = i
+		case !inWord(i) && start >= 0:
+			words = append(words, string(rs[start:i]))
+			start = -1
+		}
+	}
+	if start >= 0 {
+		words = append(words, string(rs[start:]))
+	}
+	if !haveSegDict() {
+		return words
+	}
+	var segmented []string
+	for _, w := range words {
+		if hasCJK(w) {
+			segmented = append(segmented, segment(w)...)
+		} else {
+			segmented = append(segmented, w)
+		}
+	}
+	return segmented
+}
+
+// heardWords tracks the words pronounced this session, so a sentence doesn't keep repeating "the".
+type heardWords struct {
+	mu    sync.Mutex
+	words map[string]bool
+}
+
+func (h *heardWords) heard(word string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.words[word]
+}
+
+// hear marks word as heard.
+func (h *heardWords) hear(word string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.words == nil {
+		h.words = map[string]bool{}
+	}
+	h.words[word] = true
+}
+
+// lookupCopied looks up s as copied (or typed): a sentence (per maybeSentence) is translated per -yt
+// and -gt, then read word by word with -sentences, or else looked up whole, on forvo only.
+func lookupCopied(s string, heard *heardWords, opts lookupOpts) error {
+	if maybeSentence(s, opts.options) {
+		msgf("looking up sentence `%v`...\n", s)
+		lookupSentence(s, opts.options)
+		opts.onlyForvo = true
+		if opts.sentences {
+			return readSentence(s, heard, opts)
+		}
+	}
+	return lookupFancy(s, opts)
+}
+
+// readSentence pronounces the words of s one after another (all of them, if it's a repeat).
+func readSentence(s string, heard *heardWords, opts lookupOpts) error {
+	lang := ""
+	if len(opts.langs) > 0 {
+		lang = opts.langs[0]
+	} else if len(opts.chain) > 0 {
+		lang = opts.chain[0]
+	}
+	opts.inSentence = true
+	opts.onlyForvo = false // each word is a word, with -fallback &c
+	opts.pick = 0
+	var words []string
+	seen := map[string]bool{}
+	for _, w := range tokenize(s) {
+		if w = normalizeWord(w, lang); w != "" && !seen[w] && (!heard.heard(w) || opts.repeat) {
+			seen[w] = true
+			words = append(words, w)
+		}
+	}
+	if len(words) == 0 {
+		msg("heard all these words already")
+		return nil
+	}
+	msg("words:", strings.Join(words, " "))
+	var firstErr error
+	for i, w := range words {
+		if !opts.keepGoing() {
+			break
+		}
+		if i > 0 {
+			time.Sleep(opts.gap)
+		}
+		if err := lookupFancy(w, opts); err != nil {
+			msgf("error looking up `%v`: %v\n", w, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		} else if opts.keepGoing() {
+			heard.hear(w) // only once it's been said, so it's tried again next time otherwise
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadSentenceFallback(t *testing.T) {
+	defer useFixtures(t)()
+	defer func(l, f, say string, g time.Duration) {
+		*lang, *fallback, sayCommand, *gap = l, f, say, g
+	}(*lang, *fallback, sayCommand, *gap)
+	*lang, *fallback, *gap = "de", "Anna", 0
+	for _, w := range []string{"nix", "da"} {
+		if err := saveRespToCache(Req{w, "de"}, Resp{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	opts := lookupOpts{
+		options:       currentOptions(),
+		onlyForvo:     true, // as for sentences from the clipboard
+		getPlayCount:  func(string) int { return 0 },
+		incrPlayCount: func(string) {},
+		keepGoing:     func() bool { return true },
+	}
+	var heard heardWords
+
+	// the missing words go to -fallback, which fails, so they're not heard
+	sayCommand = "false"
+	err := readSentence("Nix da, nix!", &heard, opts)
+	if err == nil || !strings.Contains(err.Error(), "could not 'say'") {
+		t.Errorf("expected the missing words to be said with -fallback, got error %v", err)
+	}
+	if heard.heard("nix") || heard.heard("da") {
+		t.Error("words that failed were marked heard")
+	}
+
+	sayCommand = "true"
+	if err := readSentence("Nix da, nix!", &heard, opts); err != nil {
+		t.Error(err)
+	}
+	if !heard.heard("nix") || !heard.heard("da") {
+		t.Error("words said with -fallback weren't marked heard")
+	}
+}
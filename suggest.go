@@ -0,0 +1,189 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+var suggestFlag = flag.Bool("suggest", true, "when a word has no pronunciations, suggest similar words from the cache")
+var suggestSearch = flag.Bool("suggestsearch", false, "with -suggest, also ask forvo's search for similar words (an api request per miss)")
+
+const maxSuggestions = 8
+
+// baseRune strips any diacritics from a precomposed letter: 'ệ' -> 'e'.
+func baseRune(r rune) rune {
+	for {
+		d, ok := decompositions[r]
+		if !ok {
+			return r
+		}
+		r = d[0]
+	}
+}
+
+// foldDiacritics strips diacritics, precomposed or combining: "café" -> "cafe".
+func foldDiacritics(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		b.WriteRune(baseRune(r))
+	}
+	return b.String()
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+// maxTypos is how far off a cached word may be to be suggested.
+func maxTypos(word string) int {
+	n := utf8.RuneCountInString(word)
+	switch {
+	case n <= 4:
+		return 1
+	case n <= 8:
+		return 2
+	}
+	return 3
+}
+
+// cachedWord is a cached word with pronunciations, and the same without diacritics.
+type cachedWord struct{ word, folded string }
+
+// pronounced indexes the cached words with pronunciations, by language, read from the cache the first
+// time a language is needed and kept up to date as we save pronunciation lists.
+var pronounced struct {
+	sync.Mutex
+	byLang map[string][]cachedWord
+}
+
+func pronouncedWords(lang string) []cachedWord {
+	pronounced.Lock()
+	defer pronounced.Unlock()
+	if pronounced.byLang == nil {
+		pronounced.byLang = map[string][]cachedWord{}
+	}
+	words, ok := pronounced.byLang[lang]
+	if !ok {
+		for _, w := range cachedWords(lang) {
+			if resp, err := getCachedResp(Req{w, lang}); err == nil && len(resp.Items) > 0 {
+				words = append(words, cachedWord{w, foldDiacritics(w)})
+			}
+		}
+		pronounced.byLang[lang] = words
+	}
+	return words
+}
+
+// notePronounced adds a newly cached word to the index, if it's been read.
+func notePronounced(req Req, resp Resp) {
+	pronounced.Lock()
+	defer pronounced.Unlock()
+	words, ok := pronounced.byLang[req.LangCode]
+	if !ok || len(resp.Items) == 0 {
+		return
+	}
+	w := sanitizeFname(req.Word)
+	for _, cw := range words {
+		if cw.word == w {
+			return
+		}
+	}
+	pronounced.byLang[req.LangCode] = append(words, cachedWord{w, foldDiacritics(w)})
+}
+
+// suggest finds words like req.Word that might have pronunciations: first the same word with different
+// diacritics, then (if search, per -suggestsearch) forvo's search results, then near misses from the cache.
+func suggest(req Req, search bool) []string {
+	var sugg []string
+	seen := map[string]bool{req.Word: true}
+	add := func(w string) {
+		if !seen[w] && len(sugg) < maxSuggestions {
+			seen[w] = true
+			sugg = append(sugg, w)
+		}
+	}
+	folded := foldDiacritics(req.Word)
+	type near struct {
+		word string
+		dist int
+	}
+	var nears []near
+	for _, cw := range pronouncedWords(req.LangCode) {
+		w, fw := cw.word, cw.folded
+		if w == req.Word {
+			continue
+		}
+		if fw == folded {
+			add(w)
+			continue
+		}
+		if d := levenshtein(fw, folded); d <= maxTypos(req.Word) {
+			nears = append(nears, near{w, d})
+		}
+	}
+	if search {
+		if words, err := SearchWords(req.LangCode, req.Word, true); err == nil {
+			for _, w := range words {
+				add(normalizeWord(w.Word, req.LangCode))
+			}
+		} else {
+			msg("could not search forvo for suggestions:", err)
+		}
+	}
+	sort.SliceStable(nears, func(i, j int) bool { return nears[i].dist < nears[j].dist })
+	for _, n := range nears {
+		add(n.word)
+	}
+	return sugg
+}
+
+func printSuggestions(sugg []string, interactive bool) {
+	if len(sugg) == 0 {
+		return
+	}
+	if !interactive {
+		msg("did you mean:", strings.Join(sugg, ", "))
+		return
+	}
+	var b strings.Builder
+	b.WriteString("did you mean:")
+	for i, s := range sugg {
+		fmt.Fprintf(&b, " s%d) %s", i+1, s)
+	}
+	msg(b.String(), " (type s1, s2... to play)")
+}
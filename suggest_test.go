@@ -0,0 +1,27 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSuggestFromCache(t *testing.T) {
+	defer useFixtures(t)()
+	defer func() { pronounced.byLang = nil }()
+	pronounced.byLang = nil
+	for _, w := range []string{"café", "cage"} {
+		if err := saveRespToCache(Req{w, "fr"}, Resp{[]Pronunciation{{Word: w}}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := suggest(Req{"cafe", "fr"}, false), []string{"café", "cage"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("suggest(cafe) = %q, want %q", got, want)
+	}
+	// a word cached later is suggested too, without reading the cache again
+	CacheResp(Req{"ich", "de"})
+	pronouncedWords("de")
+	notePronounced(Req{"ach", "de"}, Resp{[]Pronunciation{{Word: "ach"}}})
+	if got, want := suggest(Req{"ech", "de"}, false), []string{"ich", "ach"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("suggest(ech) = %q, want %q", got, want)
+	}
+}
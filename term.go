@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unicode"
+)
+
+// the terminal settings to restore on exit, if we've changed them
+var savedTerminal struct {
+	sync.Mutex
+	state  string
+	atExit []func() // more to undo first, like switching to the alternate screen
+}
+
+// keys from escape sequences, as returned by readKey
+const (
+	keyUp   = -1
+	keyDown = -2
+)
+
+// atExit has restoreTerminal call f too.
+func atExit(f func()) {
+	savedTerminal.Lock()
+	defer savedTerminal.Unlock()
+	savedTerminal.atExit = append(savedTerminal.atExit, f)
+}
+
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// cbreakTerminal turns off line buffering and echo on stdin (keeping ^C), so we can edit lines ourselves.
+func cbreakTerminal() error {
+	saved, err := stty("-g")
+	if err != nil {
+		return err
+	}
+	if _, err := stty("-icanon", "-echo", "min", "1", "time", "0"); err != nil {
+		return err
+	}
+	savedTerminal.Lock()
+	savedTerminal.state = saved
+	savedTerminal.Unlock()
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	go func() {
+		sig := <-sigs
+		restoreTerminal()
+		fmt.Fprintln(os.Stderr)
+		if s, ok := sig.(syscall.Signal); ok {
+			os.Exit(128 + int(s))
+		}
+		os.Exit(1)
+	}()
+	return nil
+}
+
+// restoreTerminal undoes cbreakTerminal; it's fine to call it either way.
+func restoreTerminal() {
+	savedTerminal.Lock()
+	defer savedTerminal.Unlock()
+	for _, f := range savedTerminal.atExit {
+		f()
+	}
+	savedTerminal.atExit = nil
+	if savedTerminal.state != "" {
+		stty(savedTerminal.state)
+		savedTerminal.state = ""
+	}
+}
+
+// lineReader reads lines from stdin, with line editing and history when it's a terminal.
+// What it echoes goes to msgOut, like our messages, so -json output stays clean.
+type lineReader struct {
+	in    *bufio.Reader
+	tty   bool
+	hist  []string
+	runes chan rune // with tty, what's typed, as it's typed
+	err   error     // why runes was closed
+}
+
+// escapeWait is how long to wait for the rest of an escape sequence, before taking ESC to be just ESC.
+const escapeWait = 50 * time.Millisecond
+
+func newLineReader() *lineReader {
+	lr := &lineReader{in: bufio.NewReader(os.Stdin)}
+	if isTerminal(os.Stdin) {
+		if err := cbreakTerminal(); err != nil {
+			msg("warning: no line editing:", err)
+		} else {
+			lr.tty = true
+			lr.runes = make(chan rune, 64)
+			go lr.readRunes()
+		}
+	}
+	return lr
+}
+
+func (lr *lineReader) readRunes() {
+	for {
+		r, _, err := lr.in.ReadRune()
+		if err != nil {
+			lr.err = err
+			close(lr.runes)
+			return
+		}
+		lr.runes <- r
+	}
+}
+
+// readRune reads what's typed next, giving up (with ok false) after wait, if wait > 0.
+func (lr *lineReader) readRune(wait time.Duration) (r rune, ok bool, err error) {
+	var timeout <-chan time.Time
+	if wait > 0 {
+		timeout = time.After(wait)
+	}
+	select {
+	case r, ok := <-lr.runes:
+		if !ok {
+			return 0, false, lr.err
+		}
+		return r, true, nil
+	case <-timeout:
+		return 0, false, nil
+	}
+}
+
+// readLine reads a line, after showing the prompt if stdin is a terminal.
+func (lr *lineReader) readLine(prompt string) (string, error) {
+	if !lr.tty {
+		return lr.in.ReadString('\n')
+	}
+	fmt.Fprint(msgOut, prompt)
+	var buf []rune
+	pos := 0
+	h := len(lr.hist) // position in history; len(lr.hist) is the line being typed
+	redraw := func() {
+		fmt.Fprint(msgOut, "\r\x1b[K", prompt, string(buf))
+		if n := len(buf) - pos; n > 0 {
+			fmt.Fprintf(msgOut, "\x1b[%dD", n)
+		}
+	}
+	for {
+		r, _, err := lr.readRune(0)
+		if err != nil {
+			return "", err
+		}
+		switch r {
+		case '\r', '\n':
+			fmt.Fprintln(msgOut)
+			s := string(buf)
+			if strings.TrimSpace(s) != "" && (len(lr.hist) == 0 || lr.hist[len(lr.hist)-1] != s) {
+				lr.hist = append(lr.hist, s)
+			}
+			return s, nil
+		case 4: // ^D
+			if len(buf) == 0 {
+				return "", io.EOF
+			}
+		case 0x7f, 8: // backspace
+			if pos > 0 {
+				buf = append(buf[:pos-1], buf[pos:]...)
+				pos--
+			}
+		case 1: // ^A
+			pos = 0
+		case 5: // ^E
+			pos = len(buf)
+		case 0x0b: // ^K
+			buf = buf[:pos]
+		case 0x15: // ^U
+			buf = append([]rune{}, buf[pos:]...)
+			pos = 0
+		case 0x17: // ^W
+			i := pos
+			for i > 0 && buf[i-1] == ' ' {
+				i--
+			}
+			for i > 0 && buf[i-1] != ' ' {
+				i--
+			}
+			buf = append(buf[:i], buf[pos:]...)
+			pos = i
+		case 0x1b: // escape sequences for the arrow keys &c
+			switch lr.escape() {
+			case "A":
+				if h > 0 {
+					h--
+					buf = []rune(lr.hist[h])
+					pos = len(buf)
+				}
+			case "B":
+				if h < len(lr.hist) {
+					h++
+					buf = nil
+					if h < len(lr.hist) {
+						buf = []rune(lr.hist[h])
+					}
+					pos = len(buf)
+				}
+			case "C":
+				if pos < len(buf) {
+					pos++
+				}
+			case "D":
+				if pos > 0 {
+					pos--
+				}
+			case "H", "1~":
+				pos = 0
+			case "F", "4~":
+				pos = len(buf)
+			case "3~":
+				if pos < len(buf) {
+					buf = append(buf[:pos], buf[pos+1:]...)
+				}
+			}
+		default:
+			if unicode.IsPrint(r) {
+				buf = append(buf[:pos], append([]rune{r}, buf[pos:]...)...)
+				pos++
+			}
+		}
+		redraw()
+	}
+}
+
+// readKey reads one key press: keyUp or keyDown for those arrows, skipping other escape sequences.
+func (lr *lineReader) readKey() (rune, error) {
+	for {
+		r, _, err := lr.readRune(0)
+		if err != nil || r != 0x1b {
+			return r, err
+		}
+		switch lr.escape() {
+		case "A":
+			return keyUp, nil
+		case "B":
+			return keyDown, nil
+		}
+	}
+}
+
+// escape reads the rest of an escape sequence (after ESC), returning e.g. "A" for ESC [ A, or ""
+// for anything else, like ESC pressed by itself.
+func (lr *lineReader) escape() string {
+	r, ok, _ := lr.readRune(escapeWait)
+	if !ok || (r != '[' && r != 'O') {
+		return ""
+	}
+	var seq []rune
+	for {
+		r, ok, _ := lr.readRune(escapeWait)
+		if !ok {
+			return ""
+		}
+		seq = append(seq, r)
+		if r >= 0x40 && r <= 0x7e {
+			return string(seq)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+var apiBase = flag.String("api", "https://apifree.forvo.com", "base `url` of the forvo api")
+var audioHost = flag.String("audiohost", "", "download mp3s from this base `url` instead of the one forvo gives (e.g. http://localhost:8080 for forvosay fakeserver)")
+var record = flag.String("record", "", "save every http request and response to this fixture `dir`ectory, for -replay")
+var replay = flag.String("replay", "", "don't touch the network; answer http requests from the fixtures recorded (with -record) in this `dir`ectory")
+
+// httpClient is what we talk to forvo with; useTransport sets it up for -record/-replay.
+var httpClient = &http.Client{}
+
+func useTransport() {
+	switch {
+	case *replay != "":
+		httpClient = &http.Client{Transport: replayTransport{*replay}}
+	case *record != "":
+		httpClient = &http.Client{Transport: recordTransport{*record, http.DefaultTransport}}
+	default:
+		httpClient = &http.Client{}
+	}
+}
+
+// apiAddr is the -api base url, minus the ssl if -nossl.
+func apiAddr() string {
+	addr := strings.TrimSuffix(*apiBase, "/")
+	if *nossl {
+		addr = strings.Replace(addr, "https://", "http://", 1)
+	}
+	return addr
+}
+
+// audioURL points an mp3 url at -audiohost, if given.
+func audioURL(s string) string {
+	if *audioHost == "" {
+		return s
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return s
+	}
+	return strings.TrimSuffix(*audioHost, "/") + u.RequestURI()
+}
+
+// fixture is one recorded http exchange.
+type fixture struct {
+	Method string
+	URL    string // with the api key masked
+	Status int
+	Header http.Header
+	Text   string `json:",omitempty"` // the body, if it's utf-8 (so it's readable)
+	Body   []byte `json:",omitempty"` // otherwise
+}
+
+func newFixture(method, url string, status int, header http.Header, body []byte) fixture {
+	f := fixture{Method: method, URL: url, Status: status, Header: header}
+	if utf8.Valid(body) {
+		f.Text = string(body)
+	} else {
+		f.Body = body
+	}
+	return f
+}
+
+func (f fixture) body() []byte {
+	if f.Body != nil {
+		return f.Body
+	}
+	return []byte(f.Text)
+}
+
+var keyInURL = regexp.MustCompile(`/key/[^/]+/`)
+
+// fixtureFname names the fixture for a request; the api key isn't part of it, so recordings replay with any key.
+func fixtureFname(dir string, req *http.Request) (string, string) {
+	u := keyInURL.ReplaceAllString(req.URL.String(), "/key/KEY/")
+	sum := fmt.Sprintf("%x", sha256.Sum256([]byte(req.Method+" "+u)))
+	return filepath.Join(dir, sum[:16]+".json"), u
+}
+
+type recordTransport struct {
+	dir  string
+	next http.RoundTripper
+}
+
+func (t recordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	fname, u := fixtureFname(t.dir, req)
+	buf, err := json.MarshalIndent(newFixture(req.Method, u, resp.StatusCode, resp.Header, body), "", "\t")
+	if err == nil {
+		err = os.MkdirAll(t.dir, 0777)
+	}
+	if err == nil {
+		err = ioutil.WriteFile(fname, buf, 0666)
+	}
+	if err != nil {
+		msg("warning: could not record fixture:", err)
+	}
+	return resp, nil
+}
+
+type replayTransport struct {
+	dir string
+}
+
+func (t replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	fname, u := fixtureFname(t.dir, req)
+	buf, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return nil, fmt.Errorf("no recorded response for %s %s in %s", req.Method, u, t.dir)
+	}
+	var f fixture
+	if err := json.Unmarshal(buf, &f); err != nil {
+		return nil, fmt.Errorf("bad fixture %s: %v", fname, err)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", f.Status, http.StatusText(f.Status)),
+		StatusCode:    f.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        f.Header,
+		Body:          ioutil.NopCloser(bytes.NewReader(f.body())),
+		ContentLength: int64(len(f.body())),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,392 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"unicode/utf8"
+)
+
+var tui = flag.Bool("tui", false, "full-screen terminal ui: list every pronunciation of the word copied to the clipboard, to play any of them with the arrow keys")
+
+// tuiWord is the word on screen, with its pronunciations.
+type tuiWord struct {
+	raw    string
+	req    Req
+	items  []Pronunciation
+	cached map[int64]bool // by id: whether an item's mp3 is in the cache
+	err    error
+	done   bool
+}
+
+// tuiLog keeps the last few messages (from msg and msgf) to show at the bottom of the screen.
+type tuiLog struct {
+	mu    sync.Mutex
+	lines []string
+	dirty chan<- bool
+}
+
+func (l *tuiLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			l.lines = append(l.lines, line)
+		}
+	}
+	if len(l.lines) > 3 {
+		l.lines = l.lines[len(l.lines)-3:]
+	}
+	l.mu.Unlock()
+	select {
+	case l.dirty <- true:
+	default:
+	}
+	return len(p), nil
+}
+
+func (l *tuiLog) last() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string{}, l.lines...)
+}
+
+type tuiState struct {
+	playing int64 // id of the pronunciation playing now; first, so it's 64-bit aligned for atomics on 386 and arm
+
+	mu         sync.Mutex
+	cur        *tuiWord
+	sel        int
+	history    []string // recent lookups, newest last
+	rating     bool     // waiting for the 1-5 after r
+	ratings    map[string]int
+	rows, cols int // the terminal's size, as of the last SIGWINCH
+
+	looks         int32 // lookups started, so older ones know to stop
+	heard         heardWords
+	getPlayCount  func(string) int
+	incrPlayCount func(string)
+	log           *tuiLog
+	dirty         chan bool
+}
+
+// runTUI looks up words from the clipboard forever, like lookupForever, but full-screen.
+func runTUI() {
+	if !isTerminal(os.Stdin) || !isTerminal(os.Stdout) {
+		fatal("-tui needs a terminal")
+	}
+	lr := newLineReader()
+	if !lr.tty {
+		fatal("-tui needs a terminal that stty can set up")
+	}
+	fmt.Print("\x1b[?1049h\x1b[?25l") // alternate screen, no cursor
+	atExit(func() { fmt.Print("\x1b[?25h\x1b[?1049l") })
+	dirty := make(chan bool, 1)
+	st := &tuiState{log: &tuiLog{dirty: dirty}, dirty: dirty, ratings: allRatings()}
+	st.getPlayCount, st.incrPlayCount = trackPlayCounts()
+	st.rows, st.cols = terminalSize()
+	*jsonOut = false // the screen is the output; -json reports on stdout would only draw over it
+	msgOut = st.log
+	winch := make(chan os.Signal, 1)
+	signal.Notify(winch, syscall.SIGWINCH)
+	go func() {
+		for range winch {
+			rows, cols := terminalSize()
+			st.mu.Lock()
+			st.rows, st.cols = rows, cols
+			st.mu.Unlock()
+			st.redraw()
+		}
+	}()
+	if *prefetchShare > 0 {
+		go prefetchForever()
+	}
+	pressed := make(chan rune)
+	go func() {
+		for {
+			k, err := lr.readKey()
+			if err != nil {
+				close(pressed)
+				return
+			}
+			pressed <- k
+		}
+	}()
+	words := make(chan string)
+	go watchClipboard(words)
+	st.draw()
+	for {
+		select {
+		case k, ok := <-pressed:
+			if !ok || k == 'q' {
+				restoreTerminal()
+				os.Exit(0)
+			}
+			st.key(k)
+		case w := <-words:
+			go st.lookup(w)
+		case <-dirty:
+		}
+		st.draw()
+	}
+}
+
+// watchClipboard sends what's copied to the clipboard (besides what's there at first).
+func watchClipboard(words chan<- string) {
+	prev, _ := readClipboard()
+	prev = strings.TrimSpace(prev)
+	cw := newClipWatcher()
+	for {
+		cw.wait(nil)
+		s, err := readClipboard()
+		s = strings.TrimSpace(s)
+		if err != nil || s == prev || s == "" {
+			cw.changed(false)
+			continue
+		}
+		cw.changed(true)
+		prev = s
+		if shouldSkip(s, currentOptions()) {
+			msg("skipping word that looks like a password or very long body of text")
+			continue
+		}
+		words <- s
+	}
+}
+
+func (st *tuiState) redraw() {
+	select {
+	case st.dirty <- true:
+	default:
+	}
+}
+
+// lookup looks s up like the clipboard loop does, showing the pronunciations it finds.
+func (st *tuiState) lookup(s string) {
+	this := atomic.AddInt32(&st.looks, 1)
+	langs, script, _ := routeLangs(s)
+	tw := &tuiWord{raw: s}
+	st.mu.Lock()
+	st.cur, st.sel = tw, 0
+	st.history = append(st.history, s)
+	st.mu.Unlock()
+	st.redraw()
+	var played int64
+	err := lookupCopied(s, &st.heard, lookupOpts{
+		options:       currentOptions(),
+		langs:         langs,
+		script:        script,
+		getPlayCount:  st.getPlayCount,
+		incrPlayCount: st.incrPlayCount,
+		keepGoing:     func() bool { return atomic.LoadInt32(&st.looks) == this },
+		found: func(req Req, items []Pronunciation) {
+			cached := map[int64]bool{}
+			for _, item := range items {
+				if _, err := os.Stat(req.CacheMP3Fname(item.Index)); err == nil {
+					cached[item.Id] = true
+				}
+			}
+			st.mu.Lock()
+			if st.cur == tw {
+				tw.req, tw.items, tw.cached = req, items, cached
+				st.sel = 0
+			}
+			st.mu.Unlock()
+			st.redraw()
+		},
+		playing: func(item Pronunciation, listed []int) {
+			st.mu.Lock()
+			if st.cur == tw && tw.cached != nil {
+				tw.cached[item.Id] = true
+				for i, it := range tw.items {
+					if it.Id == item.Id {
+						st.sel = i
+					}
+				}
+			}
+			st.mu.Unlock()
+			played = item.Id
+			atomic.StoreInt64(&st.playing, item.Id)
+			st.redraw()
+		},
+	})
+	atomic.CompareAndSwapInt64(&st.playing, played, 0)
+	st.mu.Lock()
+	tw.err, tw.done = err, true
+	st.mu.Unlock()
+	st.redraw()
+}
+
+// play plays item (downloading it first if need be), stopping whatever's playing.
+func (st *tuiState) play(req Req, item Pronunciation) {
+	stopPlayback()
+	CacheMP3s(req, Resp{[]Pronunciation{item}}, func(mp3 MaybeMP3) {
+		if mp3.Err != nil {
+			msg("could not download mp3:", mp3.Err)
+			return
+		}
+		st.incrPlayCount(mp3.Fname)
+		st.mu.Lock()
+		if tw := st.cur; tw != nil && tw.req == req && tw.cached != nil {
+			tw.cached[item.Id] = true
+		}
+		st.mu.Unlock()
+		atomic.StoreInt64(&st.playing, item.Id)
+		st.redraw()
+		if err := PlayMP3(mp3.Fname); err != nil && err != errStopped {
+			msg("could not play mp3:", err, "(will delete file)")
+			os.Remove(mp3.Fname)
+		}
+		atomic.CompareAndSwapInt64(&st.playing, item.Id, 0)
+		st.redraw()
+	})
+}
+
+func (st *tuiState) key(k rune) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	tw := st.cur
+	if st.rating {
+		st.rating = false
+		if tw != nil && k >= '1' && k <= '5' && st.sel < len(tw.items) {
+			item := tw.items[st.sel]
+			if err := saveRating(item, int(k-'0')); err != nil {
+				msg("could not save rating:", err)
+			} else {
+				st.ratings[strconv.FormatInt(item.Id, 10)] = int(k - '0')
+			}
+		}
+		return
+	}
+	if k == 's' {
+		stopPlayback() // whether or not there's anything listed to have started it
+		return
+	}
+	if tw == nil || len(tw.items) == 0 {
+		return
+	}
+	switch {
+	case k == keyUp || k == 'k':
+		if st.sel > 0 {
+			st.sel--
+		}
+	case k == keyDown || k == 'j':
+		if st.sel < len(tw.items)-1 {
+			st.sel++
+		}
+	case k >= '1' && k <= '9' && int(k-'1') < len(tw.items):
+		st.sel = int(k - '1')
+		go st.play(tw.req, tw.items[st.sel])
+	case k == ' ' || k == '\n' || k == '\r':
+		go st.play(tw.req, tw.items[st.sel])
+	case k == 'r':
+		st.rating = true
+	}
+}
+
+func (st *tuiState) draw() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	rows, cols := st.rows, st.cols
+	var lines []string
+	// line adds a line of the screen, cut to fit, in style (an escape sequence)
+	line := func(style, format string, v ...interface{}) {
+		s := redact(fmt.Sprintf(format, v...))
+		if utf8.RuneCountInString(s) > cols {
+			s = string([]rune(s)[:cols])
+		}
+		if style != "" {
+			s = style + s + "\x1b[0m"
+		}
+		lines = append(lines, s)
+	}
+	histRows := 6
+	tw := st.cur
+	switch {
+	case tw == nil:
+		line("", "copy a word to look it up")
+	default:
+		pron := pronOf(tw.req)
+		line("\x1b[1m", "%s (%s)  %v", tw.raw, tw.req.LangCode, pron)
+		if tw.err != nil {
+			line("", "error: %v", tw.err)
+		} else if len(tw.items) == 0 && tw.done {
+			line("", "no pronunciations")
+		}
+		listRows := rows - histRows - 2 - len(lines)
+		first := 0
+		if st.sel >= listRows {
+			first = st.sel - listRows + 1
+		}
+		for i := first; i < len(tw.items) && i < first+listRows; i++ {
+			item := tw.items[i]
+			fname := tw.req.CacheMP3Fname(item.Index)
+			cached := " "
+			if tw.cached[item.Id] {
+				cached = "✓"
+			}
+			rating := "     "
+			if r := st.ratings[strconv.FormatInt(item.Id, 10)]; r > 0 {
+				rating = strings.Repeat("★", r) + strings.Repeat("·", 5-r)
+			}
+			mark := "  "
+			if i == st.sel {
+				mark = "> "
+			}
+			playing := " "
+			if atomic.LoadInt64(&st.playing) == item.Id {
+				playing = "♪"
+			}
+			l := fmt.Sprintf("%s%2d. %-18s %-6s %-16s %3d/%-3d %s %s %dx %s", mark, i+1, item.Username, item.Sex, item.Country, item.NumPositiveVotes, item.NumVotes, rating, cached, st.getPlayCount(fname), playing)
+			style := ""
+			if i == st.sel {
+				style = "\x1b[7m"
+			}
+			line(style, "%s", l)
+		}
+	}
+	for len(lines) < rows-histRows-2 {
+		line("", "")
+	}
+	line("\x1b[2m", "── history %s", strings.Repeat("─", 20))
+	hist := st.history
+	if len(hist) > histRows {
+		hist = hist[len(hist)-histRows:]
+	}
+	for i := len(hist) - 1; i >= 0; i-- {
+		line("", "  %s", hist[i])
+	}
+	for i := len(hist); i < histRows; i++ {
+		line("", "")
+	}
+	logs := st.log.last()
+	status := "↑/↓ choose  enter play  1-9 pick  s stop  r rate  q quit"
+	if st.rating {
+		status = "rating (1-5)?"
+	} else if len(logs) > 0 {
+		status = logs[len(logs)-1]
+	}
+	line("\x1b[2m", "%s", status)
+	fmt.Print("\x1b[H", strings.Join(lines, "\x1b[K\n"), "\x1b[K\x1b[J")
+}
+
+// terminalSize is the size of the terminal, or a guess.
+func terminalSize() (rows, cols int) {
+	rows, cols = 24, 80
+	if s, err := stty("size"); err == nil {
+		if fs := strings.Fields(s); len(fs) == 2 {
+			if r, err := strconv.Atoi(fs[0]); err == nil && r > 10 {
+				rows = r
+			}
+			if c, err := strconv.Atoi(fs[1]); err == nil && c > 20 {
+				cols = c
+			}
+		}
+	}
+	return rows, cols
+}
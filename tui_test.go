@@ -0,0 +1,23 @@
+package main
+
+import "testing"
+
+func TestTUILookup(t *testing.T) {
+	defer useFixtures(t)()
+	defer func(l string, n int) { *lang, *numSay = l, n }(*lang, *numSay)
+	*lang, *numSay = "de", 0 // nothing to play with here
+	dirty := make(chan bool, 1)
+	st := &tuiState{log: &tuiLog{dirty: dirty}, dirty: dirty, ratings: map[string]int{}}
+	st.getPlayCount, st.incrPlayCount = trackPlayCounts()
+	st.lookup("Ich!")
+	tw := st.cur
+	if !tw.done || tw.err != nil {
+		t.Fatalf("lookup not done: %+v", tw)
+	}
+	if tw.req != (Req{"ich", "de"}) || len(tw.items) != 2 {
+		t.Errorf("looked up %v, found %d pronunciations; want ich in de, 2", tw.req, len(tw.items))
+	}
+	if len(st.history) != 1 || st.history[0] != "Ich!" {
+		t.Errorf("history is %q", st.history)
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+var convTabs = flag.String("convtab", "", "comma-separated OpenCC-style conversion `files` (TSCharacters.txt, STPhrases.txt, ...) or CC-CEDICT files, to retry Chinese words in their traditional/simplified form")
+
+// convTab maps traditional to simplified (t2s) and back (s2t), for words and single characters.
+var convTab struct {
+	mu     sync.Mutex // held while loading or resetting
+	once   sync.Once
+	t2s    map[string]string
+	s2t    map[string]string
+	maxLen int // in runes
+}
+
+// resetConvTabs has the -convtab files read again when next needed.
+func resetConvTabs() {
+	convTab.mu.Lock()
+	defer convTab.mu.Unlock()
+	convTab.once = sync.Once{}
+	convTab.t2s, convTab.s2t, convTab.maxLen = nil, nil, 0
+}
+
+// convTables are the -convtab tables and the length of their longest key, read when first needed.
+func convTables() (t2s, s2t map[string]string, maxLen int) {
+	convTab.mu.Lock()
+	defer convTab.mu.Unlock()
+	convTab.once.Do(loadConvTabs)
+	return convTab.t2s, convTab.s2t, convTab.maxLen
+}
+
+func loadConvTabs() {
+	convTab.t2s = map[string]string{}
+	convTab.s2t = map[string]string{}
+	optionsMu.RLock()
+	files := *convTabs
+	optionsMu.RUnlock()
+	if files == "" {
+		return
+	}
+	for _, fname := range strings.Split(files, ",") {
+		if err := readConvTab(strings.TrimSpace(fname)); err != nil {
+			msg("warning: could not read -convtab file:", err)
+		}
+	}
+}
+
+func addConv(m map[string]string, from, to string) {
+	if from == "" || to == "" || from == to {
+		return
+	}
+	if _, ok := m[from]; !ok {
+		m[from] = to
+	}
+	if n := utf8.RuneCountInString(from); n > convTab.maxLen {
+		convTab.maxLen = n
+	}
+}
+
+// readConvTab reads an OpenCC table ("from<TAB>to other-to" lines; the file name says which direction,
+// ST... being simplified to traditional) or a CC-CEDICT file ("Trad Simp [pinyin] /gloss/" lines).
+func readConvTab(fname string) error {
+	r, err := openDictFile(fname)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	fromSimp := strings.HasPrefix(strings.ToUpper(filepath.Base(fname)), "ST")
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var trad, simp string
+		if i := strings.IndexByte(line, '\t'); i >= 0 {
+			from, tos := line[:i], strings.Fields(line[i+1:])
+			if len(tos) == 0 {
+				continue
+			}
+			trad, simp = from, tos[0]
+			if fromSimp {
+				trad, simp = tos[0], from
+			}
+		} else {
+			fs := strings.SplitN(line, " ", 3)
+			if len(fs) < 2 {
+				continue
+			}
+			trad, simp = fs[0], fs[1]
+		}
+		addConv(convTab.t2s, trad, simp)
+		addConv(convTab.s2t, simp, trad)
+	}
+	return sc.Err()
+}
+
+// convert rewrites s with m (whose longest key is maxLen runes), longest match first.
+func convert(s string, m map[string]string, maxLen int) string {
+	rs := []rune(s)
+	var b strings.Builder
+	for i := 0; i < len(rs); {
+		n := maxLen
+		if n > len(rs)-i {
+			n = len(rs) - i
+		}
+		for ; n > 1; n-- {
+			if _, ok := m[string(rs[i:i+n])]; ok {
+				break
+			}
+		}
+		if to, ok := m[string(rs[i:i+n])]; ok {
+			b.WriteString(to)
+		} else {
+			b.WriteString(string(rs[i : i+n]))
+		}
+		i += n
+	}
+	return b.String()
+}
+
+func hasHan(s string) bool {
+	for _, r := range s {
+		if unicode.Is(unicode.Han, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// chineseVariants are the simplified and traditional forms of word that differ from it.
+func chineseVariants(word string) []string {
+	if !hasHan(word) {
+		return nil
+	}
+	t2s, s2t, maxLen := convTables()
+	var vs []string
+	for _, m := range []map[string]string{t2s, s2t} {
+		if v := convert(word, m, maxLen); v != word && !contains(vs, v) {
+			vs = append(vs, v)
+		}
+	}
+	return vs
+}
+
+func contains(ss []string, s string) bool {
+	for _, x := range ss {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// variantFname names the word that req's pronunciations are found under, when forvo only has them
+// under the other Chinese script.
+func variantFname(req Req) string {
+	return req.CacheDir() + "/.variant"
+}
+
+func cachedVariant(req Req) (Req, bool) {
+	buf, err := ioutil.ReadFile(variantFname(req))
+	if err != nil || len(buf) == 0 {
+		return req, false
+	}
+	return Req{strings.TrimSpace(string(buf)), req.LangCode}, true
+}
+
+func linkVariant(req, to Req) {
+	err := os.MkdirAll(req.CacheDir(), 0777)
+	if err == nil {
+		err = ioutil.WriteFile(variantFname(req), []byte(to.Word+"\n"), 0666)
+	}
+	if err != nil {
+		msg("warning: could not link cached variant:", err)
+	}
+}
+
+// resolveVariant looks req up in the other Chinese script, once req itself has no pronunciations.
+func resolveVariant(req Req) (Req, *Resp, bool) {
+	if to, ok := cachedVariant(req); ok && !*refreshCache {
+		if resp, err := CacheResp(to); err == nil && len(resp.Items) > 0 {
+			return to, resp, true
+		}
+	}
+	for _, v := range chineseVariants(req.Word) {
+		req2 := Req{v, req.LangCode}
+		resp2, err := CacheResp(req2)
+		if err != nil {
+			msgf("could not try `%v` instead: %v\n", v, err)
+			break
+		}
+		if len(resp2.Items) > 0 {
+			msgf("no pronunciations of `%v`; using `%v`\n", req.Word, v)
+			linkVariant(req, req2)
+			return req2, resp2, true
+		}
+	}
+	return req, nil, false
+}
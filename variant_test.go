@@ -0,0 +1,79 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// useConvTabs sets -convtab to a couple of small tables, returning a func to put things back.
+func useConvTabs(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "forvosay-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := filepath.Join(dir, "TSCharacters.txt")
+	st := filepath.Join(dir, "STPhrases.txt")
+	if err := ioutil.WriteFile(ts, []byte("電\t电\n腦\t脑\n頭\t头\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(st, []byte("头发\t頭髮 頭發\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := *convTabs
+	*convTabs = ts + "," + st
+	resetConvTabs()
+	return func() {
+		*convTabs = old
+		resetConvTabs()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestChineseVariants(t *testing.T) {
+	defer useConvTabs(t)()
+	for _, tc := range []struct {
+		word string
+		want []string
+	}{
+		{"電腦", []string{"电脑"}},
+		{"电脑", []string{"電腦"}},
+		{"头发", []string{"頭髮"}},
+		{"hund", nil},
+	} {
+		if got := chineseVariants(tc.word); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("chineseVariants(%q) = %q, want %q", tc.word, got, tc.want)
+		}
+	}
+}
+
+func TestResolveVariant(t *testing.T) {
+	defer useFixtures(t)()
+	restore := useConvTabs(t)
+	defer func() { restore() }()
+	simp, trad := Req{"电脑", "zh"}, Req{"電腦", "zh"}
+	if err := saveRespToCache(simp, Resp{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := saveRespToCache(trad, Resp{[]Pronunciation{{Word: "電腦"}}}); err != nil {
+		t.Fatal(err)
+	}
+	req, resp, err := resolve(simp.Word, "zh", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req != trad || len(resp.Items) != 1 {
+		t.Errorf("resolve(%q) = %v %+v, want %v", simp.Word, req, resp, trad)
+	}
+	if to, ok := cachedVariant(simp); !ok || to != trad {
+		t.Errorf("cached variant of %v = %v %v, want %v", simp, to, ok, trad)
+	}
+	// with the link in the cache, the conversion tables aren't needed any more
+	restore()
+	restore = func() {}
+	if req, _, err := resolve(simp.Word, "zh", false); err != nil || req != trad {
+		t.Errorf("without -convtab, resolve(%q) = %v %v, want %v", simp.Word, req, err, trad)
+	}
+}